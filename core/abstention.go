@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// AbstainReason explains why a prediction abstained instead of returning a label.
+type AbstainReason string
+
+const (
+	AbstainLowConfidence     AbstainReason = "low_confidence"      // the probability is below MinConfidence
+	AbstainOutOfDistribution AbstainReason = "out_of_distribution" // the input fits none of the labels
+)
+
+type distributionCheck struct {
+	InDistribution bool   `json:"in_distribution"`
+	Reason         string `json:"reason"`
+}
+
+func (distributionCheck) requiredJsonKeys() []string {
+	return []string{"in_distribution"}
+}
+
+// abstain replaces the predicted class of an abstained result. The probability and the distribution
+// are kept so callers can still see what the model would have picked.
+func abstain(result ClassificationResult, reason AbstainReason) ClassificationResult {
+	result.PredictedClass = ""
+	result.PredictedLabels = nil
+	result.Abstained = true
+	result.AbstainReason = reason
+
+	return result
+}
+
+// applyAbstention abstains from predictions below c.minConfidence and, when c.outOfDistributionCheck is
+// set, from inputs the model says fit none of the labels.
+func (c *TaoClassifier) applyAbstention(ctx context.Context, text string, result ClassificationResult) (ClassificationResult, error) {
+	// the multi-label threshold already decides which labels are confident enough
+	if !c.multiLabel && c.minConfidence > 0 && result.Probability < c.minConfidence {
+		return abstain(result, AbstainLowConfidence), nil
+	}
+
+	if !c.outOfDistributionCheck {
+		return result, nil
+	}
+
+	inDistribution, err := c.checkInDistribution(ctx, text)
+
+	if err != nil {
+		return ClassificationResult{Label: "", Probability: -1}, err
+	}
+
+	if !inDistribution {
+		return abstain(result, AbstainOutOfDistribution), nil
+	}
+
+	return result, nil
+}
+
+// checkInDistribution asks the model whether text belongs to any of the labels at all.
+func (c *TaoClassifier) checkInDistribution(ctx context.Context, text string) (bool, error) {
+	systemPrompt := fmt.Sprintf(`You are an AI assistant that checks whether a data point fits a classification task.
+	You will be given a map of classes and their corresponding descriptions.
+	Decide whether the data point belongs to any of the classes, or to none of them.
+	Respond in JSON with { "in_distribution": <true if it belongs to a class>, "reason": <short reason> }.
+	Context: %s\n`, c.formatClassDescriptors())
+
+	userPrompt := fmt.Sprintf(`Check the following text: "%s"`, text)
+
+	response, err := c.ai.GenerateTextContext(ctx, userPrompt, GenerateTextOptions{Verbose: false, System: systemPrompt, ResponseSchema: distributionCheckSchema()})
+
+	if err != nil {
+		return false, fmt.Errorf("checkInDistribution: failed to generate completions: %w", err)
+	}
+
+	if c.verbose {
+		fmt.Println("Prompt: ", userPrompt)
+		fmt.Println("Generated Text: ", response)
+	}
+
+	check, err := CleanGPTJson[distributionCheck](response)
+
+	if err != nil {
+		return false, fmt.Errorf("checkInDistribution: failed to parse response: %w", err)
+	}
+
+	return check.InDistribution, nil
+}
+
+func distributionCheckSchema() *ResponseSchema {
+	return &ResponseSchema{
+		Name: "distribution_check",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"in_distribution": map[string]interface{}{"type": "boolean"},
+				"reason":          map[string]interface{}{"type": "string"},
+			},
+			"required":             []string{"in_distribution", "reason"},
+			"additionalProperties": false,
+		},
+		Strict: true,
+	}
+}
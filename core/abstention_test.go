@@ -0,0 +1,97 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAbstention(t *testing.T) {
+	prompts := map[Label][]LabelDescription{
+		"positive": {"positive sentiment"},
+		"negative": {"negative sentiment"},
+	}
+
+	t.Run("Abstains from predictions below the minimum confidence. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"predicted_class": "positive", "probability": 0.55}`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, MinConfidence: 0.7})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Check out my new channel")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !result.Abstained || result.AbstainReason != AbstainLowConfidence || result.PredictedClass != "" {
+			t.Errorf("Expected a low confidence abstention, got %+v", result)
+		}
+
+		if result.Probability != 0.55 {
+			t.Errorf("Expected the probability to be kept, got %v", result.Probability)
+		}
+	})
+
+	t.Run("Abstains from out of distribution inputs. ", func(t *testing.T) {
+		provider := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			if strings.Contains(request.SystemPrompt(), "checks whether a data point fits") {
+				return `{"in_distribution": false, "reason": "an advertisement"}`, nil
+			}
+
+			return `{"predicted_class": "positive", "probability": 0.9}`, nil
+		})
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, OutOfDistributionCheck: true})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Buy cheap sneakers at example.com")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !result.Abstained || result.AbstainReason != AbstainOutOfDistribution {
+			t.Errorf("Expected an out of distribution abstention, got %+v", result)
+		}
+	})
+
+	t.Run("Counts abstentions in batch results. ", func(t *testing.T) {
+		provider := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			if strings.Contains(request.LastUserMessage(), "Great") {
+				return `{"predicted_class": "positive", "probability": 0.9}`, nil
+			}
+
+			return `{"predicted_class": "negative", "probability": 0.5}`, nil
+		})
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, MinConfidence: 0.7})
+		classifier.PromptTrain(prompts)
+
+		batch, err := classifier.PredictMany([]string{"Great game", "Whatever", "Great show"})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if batch.Succeeded != 3 || batch.Abstained != 1 || !batch.Items[1].Result.Abstained {
+			t.Errorf("Expected 3 succeeded with 1 abstained, got %v and %v", batch.Succeeded, batch.Abstained)
+		}
+	})
+
+	t.Run("Abstains in batched prompts. ", func(t *testing.T) {
+		provider := NewFakeProvider(`[{"id": "0", "predicted_class": "positive", "probability": 0.9}, {"id": "1", "predicted_class": "negative", "probability": 0.4}]`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, BatchSize: 2, MinConfidence: 0.7})
+		classifier.PromptTrain(prompts)
+
+		batch, err := classifier.PredictMany([]string{"Great game", "Whatever"})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if batch.Abstained != 1 || batch.Items[0].Result.Abstained || !batch.Items[1].Result.Abstained {
+			t.Errorf("Expected the second item to abstain, got %+v", batch.Items)
+		}
+	})
+}
@@ -2,39 +2,73 @@ package core
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"log"
-	"os"
-
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/option"
 )
 
 type AI struct {
-	openaiClient *openai.Client
+	provider    Provider
+	retryPolicy RetryPolicy
+	rateLimiter *RateLimiter
+}
+
+type AIOptions struct {
+	RetryPolicy *RetryPolicy // defaults to DefaultRetryPolicy()
+	RateLimit   RateLimit    // unlimited by default
 }
 
 type GenerateTextOptions struct {
-	System      string
-	Temperature float64
-	Verbose     bool
+	System         string
+	Temperature    float64
+	Verbose        bool
+	ResponseSchema *ResponseSchema // structured output where the provider supports it
+	Seed           int64           // sampling seed where the provider supports it, defaults to 1
+	MaxTokens      int             // caps the response length, 0 for the provider default
+}
+
+// NewAI returns an AI backed by OpenAI, configured from the OPENAI_API_KEY environment variable.
+func NewAI() (*AI, error) {
+	provider, err := NewOpenAIProvider()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAIWithProvider(provider), nil
 }
 
-func NewAI() *AI {
-	var openAiKey string = os.Getenv("OPENAI_API_KEY")
+func NewAIWithProvider(provider Provider, opts ...AIOptions) *AI {
+	options := AIOptions{}
 
-	if openAiKey == "" {
-		panic("OPENAI_KEY is not set. Please set the OPENAI_API_KEY environment variable.")
+	if len(opts) > 0 {
+		options = opts[0]
 	}
 
-	client := openai.NewClient(option.WithAPIKey(openAiKey))
+	retryPolicy := DefaultRetryPolicy()
+
+	if options.RetryPolicy != nil {
+		retryPolicy = options.RetryPolicy.withDefaults()
+	}
+
+	var rateLimiter *RateLimiter
+
+	if options.RateLimit.RequestsPerMinute > 0 || options.RateLimit.TokensPerMinute > 0 {
+		rateLimiter = NewRateLimiter(options.RateLimit)
+	}
 
 	return &AI{
-		openaiClient: client,
+		provider:    provider,
+		retryPolicy: retryPolicy,
+		rateLimiter: rateLimiter,
 	}
 }
 
 func (ai *AI) GenerateText(prompt string, opts ...GenerateTextOptions) (string, error) {
+	return ai.GenerateTextContext(context.Background(), prompt, opts...)
+}
+
+// GenerateTextContext is like GenerateText but passes ctx down to the provider call for cancellation and deadlines.
+func (ai *AI) GenerateTextContext(ctx context.Context, prompt string, opts ...GenerateTextOptions) (string, error) {
 	options := GenerateTextOptions{
 		Temperature: 0.5, // Default temperature
 		System:      "You are a helpful AI-assistant that generates text based on the given prompt.",
@@ -50,27 +84,12 @@ func (ai *AI) GenerateText(prompt string, opts ...GenerateTextOptions) (string,
 		fmt.Println("Generating text with prompt:", prompt)
 	}
 
-	ctx := context.Background()
-
-	params := openai.ChatCompletionNewParams{
-		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(options.System),
-			openai.UserMessage(prompt),
-		}),
-		Seed:        openai.Int(1),
-		Model:       openai.F(openai.ChatModelGPT4oMini),
-		Temperature: openai.Float(options.Temperature),
-	}
-
-	completions, err := ai.openaiClient.Chat.Completions.New(ctx, params)
+	result, err := ai.chat(ctx, prompt, options)
 
 	if err != nil {
-		log.Fatal("GenerateText: Failed to generate completions. ", err)
-		return "", err
+		return "", fmt.Errorf("GenerateText: failed to generate completions: %w", err)
 	}
 
-	result := completions.Choices[0].Message.Content
-
 	if options.Verbose {
 		fmt.Println("LLM Response: ", result)
 	}
@@ -79,6 +98,11 @@ func (ai *AI) GenerateText(prompt string, opts ...GenerateTextOptions) (string,
 }
 
 func (ai *AI) GenerateObject(prompt string, schema string, opts ...GenerateTextOptions) (any, error) {
+	return ai.GenerateObjectContext(context.Background(), prompt, schema, opts...)
+}
+
+// GenerateObjectContext is like GenerateObject but passes ctx down to the provider call for cancellation and deadlines.
+func (ai *AI) GenerateObjectContext(ctx context.Context, prompt string, schema string, opts ...GenerateTextOptions) (any, error) {
 	options := GenerateTextOptions{
 		Temperature: 0.5, // Default temperature
 		System:      "You are a helpful AI-assistant that generates text based on the given prompt.",
@@ -92,42 +116,140 @@ func (ai *AI) GenerateObject(prompt string, schema string, opts ...GenerateTextO
 		options = opts[0]
 	}
 
+	if options.ResponseSchema == nil {
+		options.ResponseSchema = parseResponseSchema(schema)
+	}
+
 	if options.Verbose {
 		fmt.Println("Generating text with prompt:", promptWithSchema)
 	}
 
-	ctx := context.Background()
+	contentStr, err := ai.chat(ctx, promptWithSchema, options)
 
-	params := openai.ChatCompletionNewParams{
+	if err != nil {
+		return nil, fmt.Errorf("GenerateObject: failed to generate completions: %w", err)
+	}
 
-		Messages: openai.F([]openai.ChatCompletionMessageParamUnion{
-			openai.SystemMessage(options.System),
-			openai.UserMessage(promptWithSchema),
-		}),
-		Seed:        openai.Int(1),
-		Model:       openai.F(openai.ChatModelGPT4oMini),
-		Temperature: openai.Float(options.Temperature),
+	if options.Verbose {
+		fmt.Println("LLM Response: ", contentStr)
 	}
 
-	completions, err := ai.openaiClient.Chat.Completions.New(ctx, params)
+	resultFinal, err := CleanGPTJson[interface{}](contentStr)
+
+	if err != nil {
+		return nil, fmt.Errorf("GenerateObject: failed to clean GPT JSON: %w", err)
+	}
 
-	contentStr := completions.Choices[0].Message.Content
+	return resultFinal, nil
+}
 
-	if options.Verbose {
-		fmt.Println("LLM Response: ", contentStr)
+// GenerateChatContext continues a conversation: messages are the user and assistant turns so far,
+// options.System is sent as the system prompt.
+func (ai *AI) GenerateChatContext(ctx context.Context, messages []Message, opts ...GenerateTextOptions) (string, error) {
+	options := GenerateTextOptions{
+		Temperature: 0.5, // Default temperature
+		System:      "You are a helpful AI-assistant that generates text based on the given prompt.",
+		Verbose:     false,
 	}
 
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if len(messages) == 0 {
+		return "", fmt.Errorf("GenerateChat: messages cannot be empty")
+	}
+
+	result, err := ai.chatMessages(ctx, messages, options)
+
 	if err != nil {
-		log.Fatal("GenerateObject: Failed to generate completions. ", err)
-		return nil, err
+		return "", fmt.Errorf("GenerateChat: failed to generate completions: %w", err)
 	}
 
-	resultFinal, err := CleanGPTJson[interface{}](contentStr)
+	if options.Verbose {
+		fmt.Println("LLM Response: ", result)
+	}
+
+	return result, nil
+}
+
+// parseResponseSchema returns schema as a ResponseSchema when it's a JSON Schema object, e.g.
+// { "type": "object", "properties": { ... } }, and nil for informal schemas that are only sent in the prompt.
+func parseResponseSchema(schema string) *ResponseSchema {
+	var parsed map[string]interface{}
+
+	if err := json.Unmarshal([]byte(schema), &parsed); err != nil {
+		return nil
+	}
+
+	if parsed["type"] != "object" {
+		return nil
+	}
+
+	return &ResponseSchema{Name: "object", Schema: parsed}
+}
+
+func (ai *AI) chat(ctx context.Context, prompt string, options GenerateTextOptions) (string, error) {
+	return ai.chatMessages(ctx, []Message{{Role: RoleUser, Content: prompt}}, options)
+}
+
+func (ai *AI) chatMessages(ctx context.Context, messages []Message, options GenerateTextOptions) (string, error) {
+	seed := options.Seed
+
+	if seed == 0 {
+		seed = 1
+	}
+
+	request := ChatRequest{
+		Messages:       append([]Message{{Role: RoleSystem, Content: options.System}}, messages...),
+		Temperature:    options.Temperature,
+		Seed:           seed,
+		ResponseSchema: options.ResponseSchema,
+		MaxTokens:      options.MaxTokens,
+	}
+
+	response, err := ai.complete(ctx, request)
 
 	if err != nil {
-		log.Fatal("GenerateObject: Failed to clean GPT JSON. ", err)
-		return nil, err
+		return "", err
 	}
 
-	return resultFinal, nil
+	if isTruncated(response.StopReason) {
+		return "", &ParseError{Raw: response.Content, Err: ErrTruncated}
+	}
+
+	return response.Content, nil
+}
+
+// isTruncated reports whether a stop reason means the response hit its token limit: "length" on OpenAI,
+// Ollama and llama.cpp, "max_tokens" on Anthropic.
+func isTruncated(stopReason string) bool {
+	return stopReason == "length" || stopReason == "max_tokens"
+}
+
+// complete sends the request through the rate limiter and retries transient failures per the retry policy.
+func (ai *AI) complete(ctx context.Context, request ChatRequest) (ChatResponse, error) {
+	for attempt := 1; ; attempt++ {
+		if ai.rateLimiter != nil {
+			if err := ai.rateLimiter.Wait(ctx, estimateTokens(request)); err != nil {
+				return ChatResponse{}, err
+			}
+		}
+
+		response, err := ai.provider.Chat(ctx, request)
+
+		if err == nil || attempt >= ai.retryPolicy.MaxAttempts || !IsRetryable(err) {
+			return response, err
+		}
+
+		delay, ok := ai.retryPolicy.delay(attempt, err)
+
+		if !ok {
+			return response, err
+		}
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return ChatResponse{}, err
+		}
+	}
 }
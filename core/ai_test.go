@@ -1,12 +1,52 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
+// newCassetteProvider returns an OpenAI provider that replays testdata/cassettes/<name>.json.
+// The committed cassettes are hand-written fixtures in the shape of OpenAI chat completions, not recordings.
+// Run the tests with TAO_RECORD_CASSETTES=1 and OPENAI_API_KEY set to replace them with recordings of the real API.
+func newCassetteProvider(t *testing.T, name string) Provider {
+	t.Helper()
+
+	cassettePath := filepath.Join("testdata", "cassettes", name+".json")
+	mode := CassetteReplay
+	apiKey := "test-key"
+
+	if os.Getenv("TAO_RECORD_CASSETTES") != "" {
+		mode = CassetteRecord
+		apiKey = os.Getenv("OPENAI_API_KEY")
+	}
+
+	transport, err := NewRecordReplayTransport(cassettePath, mode)
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	t.Cleanup(func() {
+		if err := transport.Save(); err != nil {
+			t.Errorf("Failed to save cassette: %v", err)
+		}
+	})
+
+	provider, err := NewOpenAIProvider(OpenAIProviderOptions{APIKey: apiKey, HTTPClient: transport.Client()})
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	return provider
+}
+
 func TestGenerateText(t *testing.T) {
 	t.Run("Generates some text based on arbitrary prompt. ", func(t *testing.T) {
-		ai := NewAI()
+		ai := NewAIWithProvider(newCassetteProvider(t, "generate_text"))
 
 		result, err := ai.GenerateText("Hello, what's your name?", GenerateTextOptions{Verbose: false})
 
@@ -22,7 +62,7 @@ func TestGenerateText(t *testing.T) {
 
 func TestGenerateObject(t *testing.T) {
 	t.Run("Generates an object based on the provided schema. ", func(t *testing.T) {
-		ai := NewAI()
+		ai := NewAIWithProvider(newCassetteProvider(t, "generate_object"))
 
 		schema := `{
 			"name": "string",
@@ -40,3 +80,97 @@ func TestGenerateObject(t *testing.T) {
 
 	})
 }
+
+func TestNewAIWithProvider(t *testing.T) {
+	t.Run("Delegates text generation to the provider. ", func(t *testing.T) {
+		provider := NewFakeProvider("Hello there")
+		ai := NewAIWithProvider(provider)
+
+		result, err := ai.GenerateText("Hello, what's your name?", GenerateTextOptions{System: "Be brief.", Temperature: 0.2})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if result != "Hello there" {
+			t.Errorf("Expected provider content, got %v", result)
+		}
+
+		requests := provider.Requests()
+
+		if len(requests) != 1 {
+			t.Fatalf("Expected 1 request, got %v", len(requests))
+		}
+
+		request := requests[0]
+
+		if request.Messages[0].Role != RoleSystem || request.Messages[0].Content != "Be brief." {
+			t.Errorf("Expected system message first, got %+v", request.Messages[0])
+		}
+
+		if request.Messages[1].Role != RoleUser || request.Temperature != 0.2 {
+			t.Errorf("Expected user message with temperature 0.2, got %+v", request)
+		}
+	})
+
+	t.Run("Parses objects returned by the provider. ", func(t *testing.T) {
+		ai := NewAIWithProvider(NewFakeProvider("```json\n{\"name\": \"Tao\"}\n```"))
+
+		result, err := ai.GenerateObject("Generate an object.", `{"name": "string"}`)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		object, ok := result.(map[string]interface{})
+
+		if !ok || object["name"] != "Tao" {
+			t.Errorf("Expected object with name Tao, got %v", result)
+		}
+	})
+}
+
+func TestTruncatedResponses(t *testing.T) {
+	t.Run("Returns a ParseError for a response cut off at the token limit. ", func(t *testing.T) {
+		for _, stopReason := range []string{"length", "max_tokens"} {
+			ai := NewAIWithProvider(NewScriptedFakeProvider(FakeResponse{Content: `{"name": "Ta`, StopReason: stopReason}))
+
+			_, err := ai.GenerateText("Generate an object.")
+
+			var parseErr *ParseError
+
+			if !errors.As(err, &parseErr) || !errors.Is(err, ErrTruncated) || parseErr.Raw != `{"name": "Ta` {
+				t.Errorf("Expected a ParseError wrapping ErrTruncated for %v, got %v", stopReason, err)
+			}
+		}
+	})
+}
+
+func TestGenerateChat(t *testing.T) {
+	t.Run("Sends the system prompt followed by the conversation. ", func(t *testing.T) {
+		provider := NewFakeProvider("Paris")
+		ai := NewAIWithProvider(provider)
+
+		messages := []Message{
+			{Role: RoleUser, Content: "Capital of Italy?"},
+			{Role: RoleAssistant, Content: "Rome"},
+			{Role: RoleUser, Content: "And France?"},
+		}
+
+		result, err := ai.GenerateChatContext(context.Background(), messages, GenerateTextOptions{System: "Be brief."})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if result != "Paris" {
+			t.Errorf("Expected provider content, got %v", result)
+		}
+
+		request := provider.Requests()[0]
+
+		if len(request.Messages) != 4 || request.Messages[0].Content != "Be brief." || request.Messages[2].Role != RoleAssistant {
+			t.Errorf("Expected the system prompt and 3 messages, got %+v", request.Messages)
+		}
+	})
+}
@@ -0,0 +1,216 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+const anthropicVersion = "2023-06-01"
+
+// AnthropicProvider talks to the Anthropic Messages API.
+type AnthropicProvider struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	maxTokens  int
+	httpClient *http.Client
+}
+
+type AnthropicProviderOptions struct {
+	APIKey     string       // defaults to ANTHROPIC_API_KEY
+	Model      string       // defaults to claude-3-5-haiku-latest
+	BaseURL    string       // defaults to https://api.anthropic.com
+	MaxTokens  int          // required by the Messages API, defaults to 1024
+	HTTPClient *http.Client // defaults to http.DefaultClient
+}
+
+type anthropicContentBlock struct {
+	Type  string          `json:"type"`
+	Text  string          `json:"text,omitempty"`
+	Name  string          `json:"name,omitempty"`  // tool_use blocks
+	Input json.RawMessage `json:"input,omitempty"` // tool_use blocks
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicToolChoice struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
+}
+
+type anthropicMessagesRequest struct {
+	Model       string               `json:"model"`
+	System      string               `json:"system,omitempty"`
+	Messages    []anthropicMessage   `json:"messages"`
+	MaxTokens   int                  `json:"max_tokens"`
+	Temperature float64              `json:"temperature"`
+	Tools       []anthropicTool      `json:"tools,omitempty"`
+	ToolChoice  *anthropicToolChoice `json:"tool_choice,omitempty"`
+}
+
+type anthropicMessagesResponse struct {
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Error      struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func NewAnthropicProvider(opts ...AnthropicProviderOptions) (*AnthropicProvider, error) {
+	options := AnthropicProviderOptions{}
+
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if options.APIKey == "" {
+		options.APIKey = os.Getenv("ANTHROPIC_API_KEY")
+	}
+
+	if options.APIKey == "" {
+		return nil, &ConfigError{Field: "ANTHROPIC_API_KEY", Err: ErrMissingAPIKey}
+	}
+
+	if options.Model == "" {
+		options.Model = "claude-3-5-haiku-latest"
+	}
+
+	if options.BaseURL == "" {
+		options.BaseURL = "https://api.anthropic.com"
+	}
+
+	if options.MaxTokens <= 0 {
+		options.MaxTokens = 1024
+	}
+
+	if options.HTTPClient == nil {
+		options.HTTPClient = http.DefaultClient
+	}
+
+	return &AnthropicProvider{
+		apiKey:     options.APIKey,
+		baseURL:    strings.TrimSuffix(options.BaseURL, "/"),
+		model:      options.Model,
+		maxTokens:  options.MaxTokens,
+		httpClient: options.HTTPClient,
+	}, nil
+}
+
+func (p *AnthropicProvider) Chat(ctx context.Context, request ChatRequest) (ChatResponse, error) {
+	// the Messages API takes the system prompt as a top-level field, not as a message
+	systemPrompts := []string{}
+	messages := []anthropicMessage{}
+
+	for _, message := range request.Messages {
+		if message.Role == RoleSystem {
+			systemPrompts = append(systemPrompts, message.Content)
+			continue
+		}
+
+		messages = append(messages, anthropicMessage{
+			Role:    message.Role,
+			Content: []anthropicContentBlock{{Type: "text", Text: message.Content}},
+		})
+	}
+
+	// the Messages API has no seed or logprobs parameters, so request.Seed and request.TopLogprobs are ignored
+	messagesRequest := anthropicMessagesRequest{
+		Model:       p.model,
+		System:      strings.Join(systemPrompts, "\n\n"),
+		Messages:    messages,
+		MaxTokens:   p.maxTokens,
+		Temperature: request.Temperature,
+	}
+
+	if request.MaxTokens > 0 {
+		messagesRequest.MaxTokens = request.MaxTokens
+	}
+
+	if request.ResponseSchema != nil {
+		// structured output is done by forcing a tool call whose input is the schema
+		messagesRequest.Tools = []anthropicTool{{
+			Name:        request.ResponseSchema.Name,
+			Description: "Respond with the result.",
+			InputSchema: request.ResponseSchema.Schema,
+		}}
+		messagesRequest.ToolChoice = &anthropicToolChoice{Type: "tool", Name: request.ResponseSchema.Name}
+	}
+
+	body, err := json.Marshal(messagesRequest)
+
+	if err != nil {
+		return ChatResponse{}, err
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(body))
+
+	if err != nil {
+		return ChatResponse{}, err
+	}
+
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("x-api-key", p.apiKey)
+	httpRequest.Header.Set("anthropic-version", anthropicVersion)
+
+	httpResponse, err := p.httpClient.Do(httpRequest)
+
+	if err != nil {
+		return ChatResponse{}, &ProviderError{Provider: "AnthropicProvider", Err: err}
+	}
+
+	defer httpResponse.Body.Close()
+
+	responseBody, err := io.ReadAll(httpResponse.Body)
+
+	if err != nil {
+		return ChatResponse{}, &ProviderError{Provider: "AnthropicProvider", StatusCode: httpResponse.StatusCode, Err: err}
+	}
+
+	var response anthropicMessagesResponse
+
+	err = json.Unmarshal(responseBody, &response)
+
+	if httpResponse.StatusCode != http.StatusOK {
+		message := response.Error.Message
+
+		if response.Error.Type != "" {
+			message = response.Error.Type + ": " + message
+		}
+
+		return ChatResponse{}, &ProviderError{Provider: "AnthropicProvider", StatusCode: httpResponse.StatusCode, Message: message, RetryAfter: parseRetryAfter(httpResponse.Header)}
+	}
+
+	if err != nil {
+		return ChatResponse{}, &ParseError{Raw: string(responseBody), Err: err}
+	}
+
+	content := ""
+
+	for _, block := range response.Content {
+		if block.Type == "tool_use" {
+			// the forced tool call carries the structured output, any text next to it is commentary
+			return ChatResponse{Content: string(block.Input), StopReason: response.StopReason}, nil
+		}
+
+		if block.Type == "text" {
+			content += block.Text
+		}
+	}
+
+	return ChatResponse{Content: content, StopReason: response.StopReason}, nil
+}
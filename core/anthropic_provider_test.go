@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAnthropicProviderChat(t *testing.T) {
+	t.Run("Sends the system prompt as a top-level field and joins text blocks. ", func(t *testing.T) {
+		var received anthropicMessagesRequest
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/messages" {
+				t.Errorf("Expected /v1/messages, got %v", r.URL.Path)
+			}
+
+			if r.Header.Get("x-api-key") != "test-key" {
+				t.Errorf("Expected x-api-key header, got %v", r.Header.Get("x-api-key"))
+			}
+
+			if r.Header.Get("anthropic-version") == "" {
+				t.Errorf("Expected anthropic-version header, got empty")
+			}
+
+			json.NewDecoder(r.Body).Decode(&received)
+
+			w.Write([]byte(`{"id": "msg_1", "type": "message", "role": "assistant", "content": [{"type": "text", "text": "{\"predicted_class\": "}, {"type": "text", "text": "\"dog\"}"}], "stop_reason": "end_turn"}`))
+		}))
+		defer server.Close()
+
+		provider, err := NewAnthropicProvider(AnthropicProviderOptions{APIKey: "test-key", BaseURL: server.URL})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		response, err := provider.Chat(context.Background(), ChatRequest{
+			Messages: []Message{
+				{Role: RoleSystem, Content: "You are a classifier."},
+				{Role: RoleUser, Content: "Woof"},
+			},
+			Temperature: 0.5,
+		})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if response.Content != `{"predicted_class": "dog"}` {
+			t.Errorf("Expected joined text blocks, got %v", response.Content)
+		}
+
+		if response.StopReason != "end_turn" {
+			t.Errorf("Expected end_turn, got %v", response.StopReason)
+		}
+
+		if received.System != "You are a classifier." {
+			t.Errorf("Expected top-level system prompt, got %v", received.System)
+		}
+
+		if len(received.Messages) != 1 || received.Messages[0].Role != RoleUser {
+			t.Errorf("Expected a single user message, got %+v", received.Messages)
+		}
+
+		if received.MaxTokens <= 0 {
+			t.Errorf("Expected max_tokens to be set, got %v", received.MaxTokens)
+		}
+	})
+
+	t.Run("Returns the API error message. ", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"type": "error", "error": {"type": "authentication_error", "message": "invalid x-api-key"}}`))
+		}))
+		defer server.Close()
+
+		provider, err := NewAnthropicProvider(AnthropicProviderOptions{APIKey: "bad-key", BaseURL: server.URL})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		_, err = provider.Chat(context.Background(), ChatRequest{Messages: []Message{{Role: RoleUser, Content: "Hi"}}})
+
+		if err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+	})
+
+	t.Run("Forces a tool call for the response schema and returns its input. ", func(t *testing.T) {
+		var received map[string]any
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&received)
+
+			w.Write([]byte(`{"content": [{"type": "tool_use", "id": "toolu_1", "name": "classification", "input": {"predicted_class": "positive"}}], "stop_reason": "tool_use"}`))
+		}))
+		defer server.Close()
+
+		provider, err := NewAnthropicProvider(AnthropicProviderOptions{APIKey: "test-key", BaseURL: server.URL})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		response, err := provider.Chat(context.Background(), ChatRequest{
+			Messages: []Message{{Role: RoleUser, Content: "Great game last night!"}},
+			ResponseSchema: &ResponseSchema{
+				Name:   "classification",
+				Schema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{"predicted_class": map[string]interface{}{"type": "string", "enum": []string{"negative", "positive"}}}},
+				Strict: true,
+			},
+		})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if response.Content != `{"predicted_class": "positive"}` {
+			t.Errorf("Expected the tool input, got %v", response.Content)
+		}
+
+		toolChoice, _ := received["tool_choice"].(map[string]any)
+		tools, _ := received["tools"].([]any)
+
+		if toolChoice["type"] != "tool" || toolChoice["name"] != "classification" || len(tools) != 1 {
+			t.Errorf("Expected a forced classification tool, got %v and %v", received["tool_choice"], received["tools"])
+		}
+	})
+}
@@ -0,0 +1,283 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	defaultContextWindow   = 128000 // gpt-4o-mini
+	defaultMaxOutputTokens = 4096   // within the output limit of the common models
+
+	// rough token costs used to size batches
+	batchItemOverheadTokens  = 10  // id and JSON punctuation around each input
+	batchOutputTokens        = 30  // one {"id", "predicted_class", "probability"} entry in the response, besides the label
+	batchOutputWrapperTokens = 20  // the {"predictions": [...]} object around the entries
+	batchResponseReserve     = 512 // headroom for estimation error
+
+	// how many times an input missing from a batched response is re-queued before it's predicted on its own
+	maxBatchRequeues = 2
+)
+
+type batchPromptItem struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+type batchResponse struct {
+	Predictions []batchPrediction `json:"predictions"`
+}
+
+func (batchResponse) requiredJsonKeys() []string {
+	return []string{"predictions"}
+}
+
+type batchPrediction struct {
+	ID             interface{} `json:"id"` // models sometimes echo numeric-looking ids as numbers
+	PredictedClass interface{} `json:"predicted_class"`
+	Probability    float64     `json:"probability"`
+}
+
+// predictBatched packs up to c.batchSize texts into each prompt and parses an array of predictions back.
+// Inputs missing from a response are re-queued into later batches, and predicted one by one as a last resort.
+// The inputs of a response that can't be parsed are re-queued too, in batches half as big when it was truncated.
+// With raw the predictions are neither calibrated nor abstained from, like predictText.
+func (c *TaoClassifier) predictBatched(ctx context.Context, texts []string, raw bool) (BatchResult, error) {
+	results := make([]ClassificationResult, len(texts))
+	errs := make([]error, len(texts))
+	done := make([]bool, len(texts))
+	requeues := make([]int, len(texts))
+
+	systemPrompt := c.batchSystemPrompt()
+	pending := []int{}
+
+	for index, text := range texts {
+		if text == "" {
+			errs[index] = ErrEmptyInput
+			done[index] = true
+			continue
+		}
+
+		pending = append(pending, index)
+	}
+
+	fallback := []int{}
+	limit := c.batchSize
+
+	for len(pending) > 0 {
+		batches := c.planBatches(systemPrompt, texts, pending, limit)
+		missing := make([][]int, len(batches))
+		truncated := make([]bool, len(batches))
+
+		runConcurrently(ctx, len(batches), c.concurrency, func(ctx context.Context, batchIndex int) {
+			predictions, output, err := c.predictBatch(ctx, systemPrompt, texts, batches[batchIndex])
+
+			if err != nil {
+				if c.verbose {
+					fmt.Println("predictBatched: batch failed:", err)
+				}
+
+				var parseErr *ParseError
+
+				// unparseable responses are re-queued like missing inputs, other failures are final
+				if !errors.As(err, &parseErr) {
+					for _, index := range batches[batchIndex] {
+						errs[index] = err
+						done[index] = true
+					}
+
+					return
+				}
+
+				truncated[batchIndex] = errors.Is(err, ErrTruncated)
+			}
+
+			for _, index := range batches[batchIndex] {
+				result, ok := predictions[strconv.Itoa(index)]
+
+				if !ok {
+					missing[batchIndex] = append(missing[batchIndex], index)
+					continue
+				}
+
+				result, err := c.validateLabel(result, output)
+
+				if err != nil {
+					// with LabelPolicyRetry unknown labels are asked for again, ending in a corrective PredictOne
+					if c.labelPolicy == LabelPolicyRetry {
+						missing[batchIndex] = append(missing[batchIndex], index)
+						continue
+					}
+
+					errs[index] = err
+					done[index] = true
+					continue
+				}
+
+				result = c.normalizeDistribution(result)
+
+				if raw {
+					results[index] = result
+				} else {
+					results[index], errs[index] = c.applyAbstention(ctx, texts[index], c.applyCalibration(result))
+				}
+
+				done[index] = true
+			}
+		})
+
+		if ctx.Err() != nil {
+			return finishBatch(ctx, results, errs, done)
+		}
+
+		for batchIndex, batch := range batches {
+			if truncated[batchIndex] {
+				limit = max(1, min(limit, len(batch)/2))
+			}
+		}
+
+		pending = []int{}
+
+		for _, indexes := range missing {
+			for _, index := range indexes {
+				requeues[index]++
+
+				if requeues[index] > maxBatchRequeues {
+					fallback = append(fallback, index)
+				} else {
+					pending = append(pending, index)
+				}
+			}
+		}
+	}
+
+	if len(fallback) > 0 {
+		runConcurrently(ctx, len(fallback), c.concurrency, func(ctx context.Context, i int) {
+			index := fallback[i]
+			results[index], errs[index] = c.predictText(ctx, texts[index], raw)
+			done[index] = true
+		})
+	}
+
+	return finishBatch(ctx, results, errs, done)
+}
+
+func (c *TaoClassifier) batchSystemPrompt() string {
+	return fmt.Sprintf(`You are an AI assistant that performs classification.
+	You will be given a map of predicted classes and their corresponding descriptions.
+	Use this information to classify each of the given data points.
+	You will receive a JSON array of items, each with an "id" and a "text".
+	Respond in JSON with { "predictions": [{ "id": <id>, "predicted_class": <class>, "probability": <probability> }] }, with exactly one entry per item.
+	Copy each "id" exactly as given. The label should be only from the given labels.
+	Context: %s\n`, c.predictionContext(""))
+}
+
+// planBatches splits the pending inputs into batches of at most limit inputs whose prompt and expected
+// response fit into c.contextWindow, and whose expected response fits into c.maxOutputTokens.
+func (c *TaoClassifier) planBatches(systemPrompt string, texts []string, pending []int, limit int) [][]int {
+	budget := c.contextWindow - len(systemPrompt)/4 - batchResponseReserve
+	outputTokens := c.batchItemOutputTokens()
+
+	batches := [][]int{}
+	batch := []int{}
+	batchTokens := 0
+
+	for _, index := range pending {
+		itemTokens := len(texts[index])/4 + batchItemOverheadTokens + outputTokens
+
+		if len(batch) > 0 && (len(batch) >= limit || batchTokens+itemTokens > budget || c.batchMaxTokens(len(batch)+1) > c.maxOutputTokens) {
+			batches = append(batches, batch)
+			batch = []int{}
+			batchTokens = 0
+		}
+
+		// an input that doesn't fit on its own still gets a batch, the provider will report it if it's too long
+		batch = append(batch, index)
+		batchTokens += itemTokens
+	}
+
+	if len(batch) > 0 {
+		batches = append(batches, batch)
+	}
+
+	return batches
+}
+
+// batchItemOutputTokens estimates the response tokens of one prediction entry, including the longest label.
+func (c *TaoClassifier) batchItemOutputTokens() int {
+	labels, _ := c.GetAvailableLabels()
+	longest := 0
+
+	for _, label := range labels {
+		longest = max(longest, len(label))
+	}
+
+	return batchOutputTokens + longest/4
+}
+
+// batchMaxTokens is the expected response length of a batch of size inputs, sent as the MaxTokens of the request.
+func (c *TaoClassifier) batchMaxTokens(size int) int {
+	return size*c.batchItemOutputTokens() + batchOutputWrapperTokens
+}
+
+// predictBatch sends one batched prompt and returns the parsed predictions keyed by item id and the raw response.
+func (c *TaoClassifier) predictBatch(ctx context.Context, systemPrompt string, texts []string, batch []int) (map[string]ClassificationResult, string, error) {
+	items := []batchPromptItem{}
+
+	for _, index := range batch {
+		items = append(items, batchPromptItem{ID: strconv.Itoa(index), Text: texts[index]})
+	}
+
+	itemsJson, err := json.Marshal(items)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	userPrompt := fmt.Sprintf("Classify the following items: %s", itemsJson)
+
+	text, err := c.ai.GenerateTextContext(ctx, userPrompt, GenerateTextOptions{
+		Verbose:        false,
+		System:         systemPrompt,
+		ResponseSchema: c.batchClassificationSchema(),
+		MaxTokens:      min(c.batchMaxTokens(len(batch)), c.maxOutputTokens),
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if c.verbose {
+		fmt.Println("Prompt: ", userPrompt)
+		fmt.Println("Generated Text: ", text)
+	}
+
+	response, err := CleanGPTJson[batchResponse](text)
+	predictions := response.Predictions
+
+	if err != nil {
+		// models without structured output sometimes answer with the bare array
+		var arrayErr error
+
+		predictions, arrayErr = CleanGPTJson[[]batchPrediction](text)
+
+		if arrayErr != nil {
+			return nil, "", err
+		}
+	}
+
+	results := map[string]ClassificationResult{}
+
+	for _, prediction := range predictions {
+		results[fmt.Sprint(prediction.ID)] = ClassificationResult{
+			Label:          c.targetColumn,
+			PredictedClass: prediction.PredictedClass,
+			Probability:    prediction.Probability,
+		}
+	}
+
+	return results, text, nil
+}
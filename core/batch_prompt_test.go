@@ -0,0 +1,290 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// newBatchFakeProvider classifies every item of a batched prompt by whether its text contains "Woof".
+// skip lets a test drop items from a batched response. Single-item prompts are answered too.
+func newBatchFakeProvider(skip func(call int, id string) bool) *FakeProvider {
+	var mu sync.Mutex
+	calls := 0
+
+	return NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+		mu.Lock()
+		calls++
+		call := calls
+		mu.Unlock()
+
+		userPrompt := request.LastUserMessage()
+
+		if strings.HasPrefix(userPrompt, "Classify the following text") {
+			if strings.Contains(userPrompt, "Woof") {
+				return `{"predicted_class": "dog", "probability": 0.9}`, nil
+			}
+
+			return `{"predicted_class": "cat", "probability": 0.9}`, nil
+		}
+
+		items := []batchPromptItem{}
+
+		err := json.Unmarshal([]byte(userPrompt[strings.Index(userPrompt, "["):]), &items)
+
+		if err != nil {
+			return "", err
+		}
+
+		predictions := []string{}
+
+		for _, item := range items {
+			if skip != nil && skip(call, item.ID) {
+				continue
+			}
+
+			class := "cat"
+
+			if strings.Contains(item.Text, "Woof") {
+				class = "dog"
+			}
+
+			predictions = append(predictions, fmt.Sprintf(`{"id": "%s", "predicted_class": "%s", "probability": 0.9}`, item.ID, class))
+		}
+
+		return `{"predictions": [` + strings.Join(predictions, ",\n") + "]}", nil
+	})
+}
+
+func TestPredictBatched(t *testing.T) {
+	texts := []string{"Meow", "Woof", "Meow", "Woof", "Woof", "Meow", "Meow"}
+
+	t.Run("Packs several inputs into each prompt and keeps the input order", func(t *testing.T) {
+		provider := newBatchFakeProvider(nil)
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, BatchSize: 3})
+
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"cat": {"meows"},
+			"dog": {"barks"},
+		})
+
+		batch, err := classifier.PredictMany(texts)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		results := batch.Results()
+
+		for index, text := range texts {
+			expected := "cat"
+
+			if text == "Woof" {
+				expected = "dog"
+			}
+
+			if results[index].PredictedClass != expected {
+				t.Errorf("Expected %v for item %d, got %v", expected, index, results[index].PredictedClass)
+			}
+		}
+
+		if len(provider.Requests()) != 3 {
+			t.Errorf("Expected 3 batched requests for 7 inputs, got %v", len(provider.Requests()))
+		}
+	})
+
+	t.Run("Re-queues inputs missing from a response", func(t *testing.T) {
+		// the first response drops item 1
+		provider := newBatchFakeProvider(func(call int, id string) bool {
+			return call == 1 && id == "1"
+		})
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, BatchSize: 10})
+
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"cat": {"meows"},
+			"dog": {"barks"},
+		})
+
+		batch, err := classifier.PredictMany(texts)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		results := batch.Results()
+
+		if results[1].PredictedClass != "dog" {
+			t.Errorf("Expected the re-queued item to be classified, got %v", results[1])
+		}
+
+		if len(provider.Requests()) != 2 {
+			t.Errorf("Expected a second request for the missing item, got %v requests", len(provider.Requests()))
+		}
+	})
+
+	t.Run("Falls back to single predictions when an input keeps going missing", func(t *testing.T) {
+		provider := newBatchFakeProvider(func(call int, id string) bool {
+			return id == "2"
+		})
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, BatchSize: 10})
+
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"cat": {"meows"},
+			"dog": {"barks"},
+		})
+
+		batch, err := classifier.PredictMany(texts)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		results := batch.Results()
+
+		if results[2].PredictedClass != "cat" {
+			t.Errorf("Expected the item to be classified on its own, got %v", results[2])
+		}
+
+		requests := provider.Requests()
+
+		if len(requests) != maxBatchRequeues+2 {
+			t.Errorf("Expected %d batched requests and 1 single request, got %v requests", maxBatchRequeues+1, len(requests))
+		}
+	})
+
+	t.Run("Re-queues an unparseable response that wasn't truncated without halving the batch", func(t *testing.T) {
+		batched := newBatchFakeProvider(nil)
+		calls := 0
+
+		provider := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			if calls++; calls == 1 {
+				return "not json", nil
+			}
+
+			response, err := batched.Chat(context.Background(), request)
+
+			return response.Content, err
+		})
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, BatchSize: 8})
+
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"cat": {"meows"},
+			"dog": {"barks"},
+		})
+
+		batch, err := classifier.PredictMany(texts)
+
+		if err != nil || batch.Succeeded != len(texts) {
+			t.Fatalf("Expected every input to be classified, got %v and %v", batch.Items, err)
+		}
+
+		if requests := provider.Requests(); len(requests) != 2 || strings.Count(requests[1].LastUserMessage(), `"id"`) != len(texts) {
+			t.Errorf("Expected the inputs to be sent again in one batch, got %v requests", len(requests))
+		}
+	})
+
+	t.Run("Halves the batch after a truncated response and caps the response length", func(t *testing.T) {
+		provider := truncatingProvider{newBatchFakeProvider(nil)}
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, BatchSize: 8})
+
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"cat": {"meows"},
+			"dog": {"barks"},
+		})
+
+		batch, err := classifier.PredictMany(texts)
+
+		if err != nil || batch.Succeeded != len(texts) {
+			t.Fatalf("Expected every input to be classified, got %v and %v", batch.Items, err)
+		}
+
+		requests := provider.Requests()
+
+		// 7 inputs, then batches of 3, 3 and 1, then single-input batches for the 6 inputs of the failed ones
+		if len(requests) != 10 {
+			t.Errorf("Expected 10 batched requests, got %v", len(requests))
+		}
+
+		for _, request := range requests {
+			if strings.HasPrefix(request.LastUserMessage(), "Classify the following text") {
+				t.Errorf("Expected no single predictions, got %v", request.LastUserMessage())
+			}
+
+			if items := strings.Count(request.LastUserMessage(), `"id"`); request.MaxTokens != items*batchOutputTokens+batchOutputWrapperTokens {
+				t.Errorf("Expected MaxTokens for %d inputs, got %v", items, request.MaxTokens)
+			}
+		}
+	})
+}
+
+// truncatingProvider cuts off responses of more than 2 predictions, like a response hitting its max tokens.
+type truncatingProvider struct {
+	*FakeProvider
+}
+
+func (p truncatingProvider) Chat(ctx context.Context, request ChatRequest) (ChatResponse, error) {
+	response, err := p.FakeProvider.Chat(ctx, request)
+
+	if err != nil || strings.Count(response.Content, `"id"`) <= 2 {
+		return response, err
+	}
+
+	return ChatResponse{Content: response.Content[:len(response.Content)/2], StopReason: "length"}, nil
+}
+
+func TestPlanBatches(t *testing.T) {
+	t.Run("Sizes batches to fit the context window", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{BatchSize: 100, ContextWindow: 1000})
+
+		texts := []string{}
+		pending := []int{}
+
+		for index := range 10 {
+			texts = append(texts, strings.Repeat("a", 400)) // ~140 tokens with overhead
+			pending = append(pending, index)
+		}
+
+		batches := classifier.planBatches("", texts, pending, classifier.batchSize)
+
+		if len(batches) < 2 {
+			t.Fatalf("Expected several batches, got %v", len(batches))
+		}
+
+		for _, batch := range batches {
+			if len(batch)*(100+batchItemOverheadTokens+batchOutputTokens) > 1000-batchResponseReserve {
+				t.Errorf("Expected batch to fit the context window, got %d items", len(batch))
+			}
+		}
+	})
+
+	t.Run("Sizes batches to fit the max output tokens", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{BatchSize: 100, MaxOutputTokens: 200})
+
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"cat": {"meows"},
+			"dog": {"barks"},
+		})
+
+		texts := []string{}
+		pending := []int{}
+
+		for index := range 20 {
+			texts = append(texts, "Meow")
+			pending = append(pending, index)
+		}
+
+		batches := classifier.planBatches("", texts, pending, classifier.batchSize)
+
+		// (200 - 20 wrapper tokens) / 30 tokens per entry
+		if len(batches) != 4 || len(batches[0]) != 6 {
+			t.Errorf("Expected batches of 6 inputs, got %v", batches)
+		}
+	})
+}
@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"errors"
+)
+
+type ErrorCategory string
+
+const (
+	ErrorCategoryProvider   ErrorCategory = "provider"   // the provider call failed
+	ErrorCategoryParse      ErrorCategory = "parse"      // the model output couldn't be parsed
+	ErrorCategoryValidation ErrorCategory = "validation" // the model output was parsed but isn't acceptable
+	ErrorCategoryInput      ErrorCategory = "input"      // the input itself is invalid, e.g. empty
+	ErrorCategoryConfig     ErrorCategory = "config"
+	ErrorCategoryCanceled   ErrorCategory = "canceled" // the context was cancelled before the item completed
+	ErrorCategoryUnknown    ErrorCategory = "unknown"
+)
+
+// CategorizeError maps an error returned by the classifier to an ErrorCategory. It returns "" for nil.
+func CategorizeError(err error) ErrorCategory {
+	var providerErr *ProviderError
+	var parseErr *ParseError
+	var configErr *ConfigError
+	var validationErr *ValidationError
+
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return ErrorCategoryCanceled
+	case errors.Is(err, ErrEmptyInput), errors.Is(err, ErrInvalidInput):
+		return ErrorCategoryInput
+	case errors.As(err, &validationErr):
+		return ErrorCategoryValidation
+	case errors.As(err, &parseErr):
+		return ErrorCategoryParse
+	case errors.As(err, &providerErr):
+		return ErrorCategoryProvider
+	case errors.As(err, &configErr):
+		return ErrorCategoryConfig
+	}
+
+	return ErrorCategoryUnknown
+}
+
+// BatchItemResult is the outcome for one input of a batch prediction: either Result or Err is set.
+type BatchItemResult struct {
+	Index         int
+	Result        ClassificationResult
+	Err           error
+	ErrorCategory ErrorCategory
+	RawOutput     string // raw model output when it couldn't be parsed or validated
+}
+
+func (r BatchItemResult) OK() bool {
+	return r.Err == nil
+}
+
+// BatchResult is returned by the PredictMany* methods. Items keep the input order.
+type BatchResult struct {
+	Items     []BatchItemResult
+	Succeeded int
+	Failed    int
+	Abstained int // succeeded items whose result abstained instead of predicting a label
+}
+
+// Results returns the classification results in input order. Failed inputs have Probability -1.
+func (b BatchResult) Results() []ClassificationResult {
+	results := []ClassificationResult{}
+
+	for _, item := range b.Items {
+		results = append(results, item.Result)
+	}
+
+	return results
+}
+
+// Errors returns the failed items.
+func (b BatchResult) Errors() []BatchItemResult {
+	failed := []BatchItemResult{}
+
+	for _, item := range b.Items {
+		if !item.OK() {
+			failed = append(failed, item)
+		}
+	}
+
+	return failed
+}
+
+// newBatchResult builds a BatchResult from per-input results and errors.
+func newBatchResult(results []ClassificationResult, errs []error) BatchResult {
+	batch := BatchResult{Items: make([]BatchItemResult, len(results))}
+
+	for index, err := range errs {
+		item := BatchItemResult{Index: index, Result: results[index]}
+
+		if err != nil {
+			item.Result = ClassificationResult{Label: "", PredictedClass: "", Probability: -1}
+			item.Err = err
+			item.ErrorCategory = CategorizeError(err)
+
+			var parseErr *ParseError
+			var validationErr *ValidationError
+
+			if errors.As(err, &parseErr) {
+				item.RawOutput = parseErr.Raw
+			} else if errors.As(err, &validationErr) {
+				item.RawOutput = validationErr.Raw
+			}
+
+			batch.Failed++
+		} else {
+			batch.Succeeded++
+
+			if item.Result.Abstained {
+				batch.Abstained++
+			}
+		}
+
+		batch.Items[index] = item
+	}
+
+	return batch
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCategorizeError(t *testing.T) {
+	t.Run("Maps errors to their category. ", func(t *testing.T) {
+		cases := map[error]ErrorCategory{
+			nil:                                 "",
+			&ProviderError{StatusCode: 500}:     ErrorCategoryProvider,
+			&ParseError{Raw: "oops"}:            ErrorCategoryParse,
+			&ConfigError{Field: "TargetColumn"}: ErrorCategoryConfig,
+			ErrEmptyInput:                       ErrorCategoryInput,
+			ErrInvalidInput:                     ErrorCategoryInput,
+			fmt.Errorf("wrapped: %w", context.Canceled): ErrorCategoryCanceled,
+			errors.New("something else"):                ErrorCategoryUnknown,
+		}
+
+		for err, expected := range cases {
+			if category := CategorizeError(err); category != expected {
+				t.Errorf("Expected %v for %v, got %v", expected, err, category)
+			}
+		}
+	})
+}
+
+func TestBatchResult(t *testing.T) {
+	t.Run("Reports results, errors and counts per input. ", func(t *testing.T) {
+		_, parseErr := CleanGPTJson[ClassificationResult]("not json")
+
+		results := []ClassificationResult{
+			{PredictedClass: "cat", Probability: 0.9},
+			{},
+			{},
+		}
+
+		batch := newBatchResult(results, []error{nil, parseErr, ErrEmptyInput})
+
+		if batch.Succeeded != 1 || batch.Failed != 2 {
+			t.Errorf("Expected 1 succeeded and 2 failed, got %v and %v", batch.Succeeded, batch.Failed)
+		}
+
+		if !batch.Items[0].OK() || batch.Items[0].Result.PredictedClass != "cat" {
+			t.Errorf("Expected the first item to succeed, got %v", batch.Items[0])
+		}
+
+		if batch.Items[1].ErrorCategory != ErrorCategoryParse || batch.Items[1].RawOutput != "not json" {
+			t.Errorf("Expected a parse error with the raw output, got %v (%q)", batch.Items[1].ErrorCategory, batch.Items[1].RawOutput)
+		}
+
+		if batch.Items[2].ErrorCategory != ErrorCategoryInput || batch.Items[2].Result.Probability != -1 {
+			t.Errorf("Expected an input error with a placeholder result, got %v", batch.Items[2])
+		}
+
+		if len(batch.Results()) != 3 || len(batch.Errors()) != 2 {
+			t.Errorf("Expected 3 results and 2 errors, got %v and %v", len(batch.Results()), len(batch.Errors()))
+		}
+	})
+
+	t.Run("PredictMany reports empty inputs without failing the batch. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: newFakeClassifierProvider()})
+
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"cat": {"meows"},
+			"dog": {"barks"},
+		})
+
+		batch, err := classifier.PredictMany([]string{"Meow", ""})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if batch.Succeeded != 1 || batch.Failed != 1 || !errors.Is(batch.Items[1].Err, ErrEmptyInput) {
+			t.Errorf("Expected the empty input to fail on its own, got %v", batch.Items)
+		}
+	})
+
+	t.Run("Keeps the raw output of predictions with an unknown label. ", func(t *testing.T) {
+		raw := `{"predicted_class": "bird", "probability": 0.9}`
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: NewFakeProvider(raw)})
+
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"cat": {"meows"},
+			"dog": {"barks"},
+		})
+
+		batch, err := classifier.PredictMany([]string{"Tweet"})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if item := batch.Items[0]; item.ErrorCategory != ErrorCategoryValidation || item.RawOutput != raw {
+			t.Errorf("Expected a validation error with the raw output, got %v (%q)", item.ErrorCategory, item.RawOutput)
+		}
+	})
+
+	t.Run("PredictManyObjects reports objects that can't be marshaled without failing the batch. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: newFakeClassifierProvider()})
+
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"cat": {"meows"},
+			"dog": {"barks"},
+		})
+
+		batch, err := classifier.PredictManyObjects([]any{"Meow", make(chan int), "Woof"})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if len(batch.Items) != 3 || batch.Succeeded != 2 || batch.Failed != 1 {
+			t.Fatalf("Expected 2 succeeded and 1 failed item, got %v", batch.Items)
+		}
+
+		if batch.Items[1].ErrorCategory != ErrorCategoryInput || !errors.Is(batch.Items[1].Err, ErrInvalidInput) {
+			t.Errorf("Expected an input error for the channel, got %v", batch.Items[1])
+		}
+
+		if !batch.Items[0].OK() || !batch.Items[2].OK() {
+			t.Errorf("Expected the other objects to be predicted, got %v", batch.Items)
+		}
+	})
+}
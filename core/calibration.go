@@ -0,0 +1,485 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+)
+
+type CalibrationMethod string
+
+const (
+	CalibrationTemperature CalibrationMethod = "temperature" // p' = sigmoid(logit(p) / T)
+	CalibrationPlatt       CalibrationMethod = "platt"       // p' = sigmoid(A * logit(p) + B)
+	CalibrationIsotonic    CalibrationMethod = "isotonic"    // a non-decreasing step function fitted with pool adjacent violators
+)
+
+const (
+	defaultReliabilityBins = 10
+	calibrationEpsilon     = 1e-6
+)
+
+// Calibration maps the raw probability of a predicted class to the observed accuracy of predictions with that
+// probability. It's fitted by TaoClassifier.Calibrate and saved with the model.
+type Calibration struct {
+	Method      CalibrationMethod
+	Temperature float64   `json:",omitempty"`
+	A           float64   `json:",omitempty"`
+	B           float64   `json:",omitempty"`
+	Thresholds  []float64 `json:",omitempty"` // isotonic: lower bound of each step
+	Values      []float64 `json:",omitempty"` // isotonic: calibrated probability of each step
+}
+
+// ReliabilityBin holds the predictions whose probability falls into [Lower, Upper).
+type ReliabilityBin struct {
+	Lower          float64
+	Upper          float64
+	Count          int
+	MeanConfidence float64
+	Accuracy       float64
+}
+
+// ReliabilityReport is the data behind a reliability diagram. ECE is the expected calibration error, the
+// average gap between confidence and accuracy weighted by the number of predictions in each bin.
+type ReliabilityReport struct {
+	Bins     []ReliabilityBin
+	ECE      float64
+	Count    int
+	Accuracy float64
+}
+
+// CalibrationReport compares the reliability on the validation set before and after calibration.
+type CalibrationReport struct {
+	Calibration *Calibration
+	Before      ReliabilityReport
+	After       ReliabilityReport // in-sample, measured on the same validation set the calibration was fitted on
+}
+
+type CalibrateOptions struct {
+	Method CalibrationMethod // defaults to CalibrationTemperature
+	Bins   int               // reliability diagram bins, defaults to 10
+}
+
+func sigmoid(x float64) float64 {
+	return 1 / (1 + math.Exp(-x))
+}
+
+func logit(p float64) float64 {
+	p = math.Max(calibrationEpsilon, math.Min(1-calibrationEpsilon, p))
+
+	return math.Log(p / (1 - p))
+}
+
+// Apply returns the calibrated probability for a raw probability.
+func (cal *Calibration) Apply(probability float64) float64 {
+	switch cal.Method {
+	case CalibrationTemperature:
+		return sigmoid(logit(probability) / cal.Temperature)
+	case CalibrationPlatt:
+		return sigmoid(cal.A*logit(probability) + cal.B)
+	case CalibrationIsotonic:
+		if len(cal.Values) == 0 {
+			return probability
+		}
+
+		// the last step starting at or below the probability
+		index := sort.SearchFloat64s(cal.Thresholds, probability)
+
+		if index == len(cal.Thresholds) || cal.Thresholds[index] != probability {
+			index--
+		}
+
+		return cal.Values[max(0, index)]
+	}
+
+	return probability
+}
+
+// FitCalibration fits a calibration of the given method to the probabilities of predictions and whether
+// each prediction was correct.
+func FitCalibration(method CalibrationMethod, probabilities []float64, correct []bool) (*Calibration, error) {
+	if len(probabilities) != len(correct) {
+		return nil, fmt.Errorf("FitCalibration: got %d probabilities for %d outcomes", len(probabilities), len(correct))
+	}
+
+	if len(probabilities) < 2 {
+		return nil, fmt.Errorf("FitCalibration: at least 2 predictions are needed, got %d", len(probabilities))
+	}
+
+	switch method {
+	case CalibrationTemperature:
+		return &Calibration{Method: method, Temperature: fitTemperature(probabilities, correct)}, nil
+	case CalibrationPlatt:
+		a, b := fitPlatt(probabilities, correct)
+		return &Calibration{Method: method, A: a, B: b}, nil
+	case CalibrationIsotonic:
+		thresholds, values := fitIsotonic(probabilities, correct)
+		return &Calibration{Method: method, Thresholds: thresholds, Values: values}, nil
+	}
+
+	return nil, fmt.Errorf("FitCalibration: unknown calibration method %q", method)
+}
+
+// fitTemperature minimizes the negative log likelihood over log(T) with a golden-section search.
+func fitTemperature(probabilities []float64, correct []bool) float64 {
+	nll := func(logTemperature float64) float64 {
+		temperature := math.Exp(logTemperature)
+		loss := 0.0
+
+		for i, probability := range probabilities {
+			calibrated := math.Max(calibrationEpsilon, math.Min(1-calibrationEpsilon, sigmoid(logit(probability)/temperature)))
+
+			if correct[i] {
+				loss -= math.Log(calibrated)
+			} else {
+				loss -= math.Log(1 - calibrated)
+			}
+		}
+
+		return loss
+	}
+
+	ratio := (math.Sqrt(5) - 1) / 2
+	low, high := -4.0, 4.0
+
+	for high-low > 1e-6 {
+		left := high - ratio*(high-low)
+		right := low + ratio*(high-low)
+
+		if nll(left) < nll(right) {
+			high = right
+		} else {
+			low = left
+		}
+	}
+
+	return math.Exp((low + high) / 2)
+}
+
+// fitPlatt fits a logistic regression on logit(p) with Newton's method and a backtracking line search, using
+// Platt's smoothed targets so perfectly separable data doesn't diverge.
+func fitPlatt(probabilities []float64, correct []bool) (float64, float64) {
+	positives, negatives := 0.0, 0.0
+
+	for _, ok := range correct {
+		if ok {
+			positives++
+		} else {
+			negatives++
+		}
+	}
+
+	positiveTarget := (positives + 1) / (positives + 2)
+	negativeTarget := 1 / (negatives + 2)
+
+	xs := []float64{}
+	targets := []float64{}
+
+	for i, probability := range probabilities {
+		xs = append(xs, logit(probability))
+
+		if correct[i] {
+			targets = append(targets, positiveTarget)
+		} else {
+			targets = append(targets, negativeTarget)
+		}
+	}
+
+	softplus := func(z float64) float64 {
+		return math.Max(z, 0) + math.Log1p(math.Exp(-math.Abs(z)))
+	}
+
+	loss := func(a float64, b float64) float64 {
+		total := 0.0
+
+		for i, x := range xs {
+			f := a*x + b
+			total += targets[i]*softplus(-f) + (1-targets[i])*softplus(f)
+		}
+
+		return total
+	}
+
+	a, b := 1.0, 0.0
+	current := loss(a, b)
+
+	for range 100 {
+		gradientA, gradientB := 0.0, 0.0
+		// a small ridge keeps the step finite when all probabilities are the same
+		hessianAA, hessianAB, hessianBB := 1e-3, 0.0, 1e-3
+
+		for i, x := range xs {
+			q := sigmoid(a*x + b)
+			weight := q * (1 - q)
+
+			gradientA += (q - targets[i]) * x
+			gradientB += q - targets[i]
+			hessianAA += weight * x * x
+			hessianAB += weight * x
+			hessianBB += weight
+		}
+
+		if math.Abs(gradientA) < 1e-9 && math.Abs(gradientB) < 1e-9 {
+			break
+		}
+
+		determinant := hessianAA*hessianBB - hessianAB*hessianAB
+		stepA := (hessianBB*gradientA - hessianAB*gradientB) / determinant
+		stepB := (hessianAA*gradientB - hessianAB*gradientA) / determinant
+
+		improved := false
+
+		for stepSize := 1.0; stepSize > 1e-10; stepSize /= 2 {
+			nextA, nextB := a-stepSize*stepA, b-stepSize*stepB
+			next := loss(nextA, nextB)
+
+			if next < current {
+				a, b, current = nextA, nextB, next
+				improved = true
+				break
+			}
+		}
+
+		if !improved {
+			break
+		}
+	}
+
+	return a, b
+}
+
+// fitIsotonic fits a non-decreasing step function with the pool adjacent violators algorithm.
+func fitIsotonic(probabilities []float64, correct []bool) ([]float64, []float64) {
+	type block struct {
+		lower float64
+		sum   float64
+		count float64
+	}
+
+	indexes := make([]int, len(probabilities))
+
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	sort.Slice(indexes, func(i, j int) bool {
+		return probabilities[indexes[i]] < probabilities[indexes[j]]
+	})
+
+	blocks := []block{}
+
+	for _, index := range indexes {
+		outcome := 0.0
+
+		if correct[index] {
+			outcome = 1
+		}
+
+		current := block{lower: probabilities[index], sum: outcome, count: 1}
+
+		// merge with the previous blocks while their mean is higher
+		for len(blocks) > 0 {
+			previous := blocks[len(blocks)-1]
+
+			if previous.sum/previous.count < current.sum/current.count && previous.lower != current.lower {
+				break
+			}
+
+			current = block{lower: previous.lower, sum: previous.sum + current.sum, count: previous.count + current.count}
+			blocks = blocks[:len(blocks)-1]
+		}
+
+		blocks = append(blocks, current)
+	}
+
+	thresholds := []float64{}
+	values := []float64{}
+
+	for _, b := range blocks {
+		thresholds = append(thresholds, b.lower)
+		values = append(values, b.sum/b.count)
+	}
+
+	return thresholds, values
+}
+
+// NewReliabilityReport bins predictions by probability into equal-width bins and computes the accuracy,
+// mean confidence and expected calibration error.
+func NewReliabilityReport(probabilities []float64, correct []bool, bins int) ReliabilityReport {
+	if bins <= 0 {
+		bins = defaultReliabilityBins
+	}
+
+	report := ReliabilityReport{Bins: make([]ReliabilityBin, bins), Count: len(probabilities)}
+	confidenceSums := make([]float64, bins)
+	correctCounts := make([]float64, bins)
+	totalCorrect := 0.0
+
+	for i, probability := range probabilities {
+		bin := min(bins-1, max(0, int(probability*float64(bins))))
+
+		report.Bins[bin].Count++
+		confidenceSums[bin] += probability
+
+		if correct[i] {
+			correctCounts[bin]++
+			totalCorrect++
+		}
+	}
+
+	for bin := range report.Bins {
+		report.Bins[bin].Lower = float64(bin) / float64(bins)
+		report.Bins[bin].Upper = float64(bin+1) / float64(bins)
+
+		count := report.Bins[bin].Count
+
+		if count == 0 {
+			continue
+		}
+
+		report.Bins[bin].MeanConfidence = confidenceSums[bin] / float64(count)
+		report.Bins[bin].Accuracy = correctCounts[bin] / float64(count)
+		report.ECE += float64(count) / float64(len(probabilities)) * math.Abs(report.Bins[bin].Accuracy-report.Bins[bin].MeanConfidence)
+	}
+
+	if len(probabilities) > 0 {
+		report.Accuracy = totalCorrect / float64(len(probabilities))
+	}
+
+	return report
+}
+
+// applyCalibration replaces the probability of the predicted class with the calibrated one and rescales
+// the rest of the distribution so it still sums to 1. RawProbability keeps the uncalibrated value.
+func (c *TaoClassifier) applyCalibration(result ClassificationResult) ClassificationResult {
+	if c.calibration == nil || c.multiLabel {
+		return result
+	}
+
+	predicted, _ := result.PredictedClass.(string)
+	calibrated := c.calibration.Apply(result.Probability)
+
+	if predicted != "" && len(result.Distribution) > 1 {
+		distribution := map[Label]float64{}
+		rest := 1 - result.Distribution[predicted]
+
+		for label, probability := range result.Distribution {
+			switch {
+			case label == predicted:
+				distribution[label] = calibrated
+			case rest > 0:
+				distribution[label] = probability / rest * (1 - calibrated)
+			default:
+				distribution[label] = (1 - calibrated) / float64(len(result.Distribution)-1)
+			}
+		}
+
+		result.Distribution = distribution
+	}
+
+	result.RawProbability = result.Probability
+	result.Probability = calibrated
+
+	return result
+}
+
+// Calibrate predicts every row of a labeled validation CSV (with the target column), fits a calibration of
+// the predicted class probabilities and applies it to every later prediction. The calibration is saved with
+// the model. Use a validation set that wasn't used for training.
+func (c *TaoClassifier) Calibrate(validationDatasetPath string, opts ...CalibrateOptions) (CalibrationReport, error) {
+	return c.CalibrateContext(context.Background(), validationDatasetPath, opts...)
+}
+
+func (c *TaoClassifier) CalibrateContext(ctx context.Context, validationDatasetPath string, opts ...CalibrateOptions) (CalibrationReport, error) {
+	options := CalibrateOptions{Method: CalibrationTemperature, Bins: defaultReliabilityBins}
+
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if options.Method == "" {
+		options.Method = CalibrationTemperature
+	}
+
+	if options.Bins <= 0 {
+		options.Bins = defaultReliabilityBins
+	}
+
+	if c.multiLabel {
+		return CalibrationReport{}, &ConfigError{Field: "MultiLabel", Err: fmt.Errorf("calibration is not supported in multi-label mode")}
+	}
+
+	if c.targetColumn == "" {
+		return CalibrationReport{}, &ConfigError{Field: "TargetColumn", Err: fmt.Errorf("TargetColumn is needed to read the validation labels")}
+	}
+
+	rows, err := ReadCSVFile(validationDatasetPath)
+
+	if err != nil {
+		return CalibrationReport{}, &ConfigError{Field: "ValidationDatasetPath", Err: err}
+	}
+
+	objs := []any{}
+	actual := []string{}
+
+	for _, row := range rows {
+		rowItem := RowItem{}
+
+		for key, value := range row {
+			if key != c.targetColumn {
+				rowItem[key] = value
+			}
+		}
+
+		objs = append(objs, rowItem)
+		actual = append(actual, row[c.targetColumn])
+	}
+
+	if len(objs) == 0 {
+		return CalibrationReport{}, &ConfigError{Field: "ValidationDatasetPath", Err: fmt.Errorf("validation dataset is empty")}
+	}
+
+	// fit on the raw probabilities of every prediction, without calibration and abstention
+	batch, err := c.predictObjects(ctx, objs, true)
+
+	if err != nil {
+		return CalibrationReport{}, fmt.Errorf("Calibrate: failed to predict the validation set: %w", err)
+	}
+
+	probabilities := []float64{}
+	correct := []bool{}
+
+	for _, item := range batch.Items {
+		if !item.OK() {
+			if c.verbose {
+				fmt.Println("Calibrate: skipping failed prediction:", item.Err)
+			}
+
+			continue
+		}
+
+		probabilities = append(probabilities, item.Result.Probability)
+		correct = append(correct, normalizeLabel(fmt.Sprint(item.Result.PredictedClass)) == normalizeLabel(actual[item.Index]))
+	}
+
+	fitted, err := FitCalibration(options.Method, probabilities, correct)
+
+	if err != nil {
+		return CalibrationReport{}, fmt.Errorf("Calibrate: %w", err)
+	}
+
+	calibrated := []float64{}
+
+	for _, probability := range probabilities {
+		calibrated = append(calibrated, fitted.Apply(probability))
+	}
+
+	c.calibration = fitted
+
+	return CalibrationReport{
+		Calibration: fitted,
+		Before:      NewReliabilityReport(probabilities, correct, options.Bins),
+		After:       NewReliabilityReport(calibrated, correct, options.Bins),
+	}, nil
+}
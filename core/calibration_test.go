@@ -0,0 +1,207 @@
+package core
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// overconfidentPredictions says 0.95 but is right 60% of the time, and 0.6 while being right 50% of the time.
+func overconfidentPredictions() ([]float64, []bool) {
+	probabilities := []float64{}
+	correct := []bool{}
+
+	for i := range 100 {
+		probabilities = append(probabilities, 0.95)
+		correct = append(correct, i%10 < 6)
+	}
+
+	for i := range 20 {
+		probabilities = append(probabilities, 0.6)
+		correct = append(correct, i%2 == 0)
+	}
+
+	return probabilities, correct
+}
+
+func TestFitCalibration(t *testing.T) {
+	probabilities, correct := overconfidentPredictions()
+
+	t.Run("Temperature scaling softens overconfident probabilities. ", func(t *testing.T) {
+		calibration, err := FitCalibration(CalibrationTemperature, probabilities, correct)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if calibration.Temperature <= 1 || calibration.Apply(0.95) >= 0.95 {
+			t.Errorf("Expected a temperature above 1, got %v", calibration.Temperature)
+		}
+	})
+
+	t.Run("Platt scaling maps probabilities to the observed accuracy. ", func(t *testing.T) {
+		calibration, err := FitCalibration(CalibrationPlatt, probabilities, correct)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if math.Abs(calibration.Apply(0.95)-0.6) > 0.02 || math.Abs(calibration.Apply(0.6)-0.5) > 0.05 {
+			t.Errorf("Expected about 0.6 and 0.5, got %v and %v", calibration.Apply(0.95), calibration.Apply(0.6))
+		}
+	})
+
+	t.Run("Isotonic regression fits a non-decreasing step function. ", func(t *testing.T) {
+		calibration, err := FitCalibration(CalibrationIsotonic, []float64{0.9, 0.5, 0.7, 0.8}, []bool{true, false, true, false})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		// 0.7 (right) and 0.8 (wrong) violate the order and are pooled
+		expected := map[float64]float64{0.4: 0, 0.5: 0, 0.75: 0.5, 0.85: 0.5, 0.95: 1}
+
+		for probability, value := range expected {
+			if calibrated := calibration.Apply(probability); calibrated != value {
+				t.Errorf("Expected %v for %v, got %v", value, probability, calibrated)
+			}
+		}
+	})
+
+	t.Run("Rejects unknown methods and too few predictions. ", func(t *testing.T) {
+		if _, err := FitCalibration("magic", probabilities, correct); err == nil {
+			t.Errorf("Expected an error for an unknown method, got nil")
+		}
+
+		if _, err := FitCalibration(CalibrationPlatt, []float64{0.9}, []bool{true}); err == nil {
+			t.Errorf("Expected an error for a single prediction, got nil")
+		}
+	})
+}
+
+func TestReliabilityReport(t *testing.T) {
+	t.Run("Computes bin stats and the expected calibration error. ", func(t *testing.T) {
+		report := NewReliabilityReport([]float64{0.95, 0.95, 0.15, 0.15}, []bool{true, false, false, false}, 10)
+
+		if len(report.Bins) != 10 || report.Bins[9].Count != 2 || report.Bins[9].Accuracy != 0.5 || report.Bins[1].Count != 2 {
+			t.Errorf("Expected 2 predictions in the first and last used bins, got %+v", report.Bins)
+		}
+
+		// half the predictions are 0.45 too confident, the other half 0.15
+		if math.Abs(report.ECE-0.3) > 1e-9 || report.Accuracy != 0.25 {
+			t.Errorf("Expected an ECE of 0.3 and accuracy 0.25, got %v and %v", report.ECE, report.Accuracy)
+		}
+	})
+}
+
+func TestCalibrate(t *testing.T) {
+	t.Run("Fits on a validation set, applies and persists the calibration. ", func(t *testing.T) {
+		validationPath := filepath.Join(t.TempDir(), "validation.csv")
+		rows := "text,animal\n"
+
+		for i := range 20 {
+			// the provider always answers cat, so only the even rows are right
+			if i%2 == 0 {
+				rows += "meow,cat\n"
+			} else {
+				rows += "woof,dog\n"
+			}
+		}
+
+		if err := os.WriteFile(validationPath, []byte(rows), 0644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		provider := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			if strings.Contains(request.LastUserMessage(), "animal") {
+				return "", &ParseError{Raw: "target column leaked into the prompt"}
+			}
+
+			return `{"predicted_class": "cat", "probability": 0.95}`, nil
+		})
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{ModelId: "test_calibrated_model", Provider: provider, TargetColumn: "animal", Concurrency: 4})
+		classifier.PromptTrain(map[Label][]LabelDescription{"cat": {"meows"}, "dog": {"barks"}})
+
+		report, err := classifier.Calibrate(validationPath, CalibrateOptions{Method: CalibrationPlatt})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if report.Before.Count != 20 || report.After.ECE >= report.Before.ECE {
+			t.Errorf("Expected the calibration to lower the ECE, got %v before and %v after", report.Before.ECE, report.After.ECE)
+		}
+
+		result, err := classifier.PredictOne("meow")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.RawProbability != 0.95 || math.Abs(result.Probability-0.5) > 0.05 {
+			t.Errorf("Expected the probability calibrated to about 0.5, got %v (raw %v)", result.Probability, result.RawProbability)
+		}
+
+		if math.Abs(result.Distribution["cat"]+result.Distribution["dog"]-1) > 1e-9 || result.Distribution["cat"] != result.Probability {
+			t.Errorf("Expected the distribution to follow the calibrated probability, got %v", result.Distribution)
+		}
+
+		if _, err := classifier.SaveModel(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		loadedClassifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider})
+
+		if _, err := loadedClassifier.LoadModel("test_calibrated_model"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		loaded := loadedClassifier.GetSavableModel().Calibration
+
+		if loaded == nil || loaded.Method != CalibrationPlatt || loaded.Apply(0.95) != report.Calibration.Apply(0.95) {
+			t.Errorf("Expected the calibration to be saved with the model, got %+v", loaded)
+		}
+	})
+	t.Run("Fits on raw predictions without changing the settings of concurrent predictions. ", func(t *testing.T) {
+		validationPath := filepath.Join(t.TempDir(), "validation.csv")
+		rows := "text,animal\n"
+
+		for range 10 {
+			rows += "meow,cat\nwoof,dog\n"
+		}
+
+		if err := os.WriteFile(validationPath, []byte(rows), 0644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		var classifier *TaoClassifier
+		changed := false
+
+		provider := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			// the fake provider answers one request at a time
+			changed = changed || classifier.minConfidence != 0.99
+
+			return `{"predicted_class": "cat", "probability": 0.95}`, nil
+		})
+
+		classifier = newTestClassifier(t, TaoClassifierOptions{Provider: provider, TargetColumn: "animal", MinConfidence: 0.99, Concurrency: 4})
+		classifier.PromptTrain(map[Label][]LabelDescription{"cat": {"meows"}, "dog": {"barks"}})
+
+		report, err := classifier.Calibrate(validationPath)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if report.Before.Count != 20 {
+			t.Errorf("Expected 20 raw predictions despite MinConfidence, got %v", report.Before.Count)
+		}
+
+		if changed {
+			t.Errorf("Expected MinConfidence to stay set while calibrating")
+		}
+	})
+}
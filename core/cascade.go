@@ -0,0 +1,194 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// CascadeStage is one backend of a CascadeClassifier.
+type CascadeStage struct {
+	Name       string         // reported in ClassificationResult.Stage, defaults to "stage <n>"
+	Provider   Provider       // backend of the stage, classifying with the prompts of the cascade's saved model
+	Classifier *TaoClassifier // classifier of the stage, used as it is instead of Provider
+	Threshold  float64        // escalate predictions with a lower probability to the next stage, ignored for the last stage
+}
+
+type CascadeOptions struct {
+	Classifier TaoClassifierOptions // options for the classifier of every stage with a Provider, Provider and ModelId are set per stage
+}
+
+// CascadeClassifier classifies with its stages in order, usually from the cheapest to the most expensive
+// model, and escalates a prediction to the next stage when it's below the stage's threshold, abstained or
+// failed. Stages with a Provider use the prompts of the same saved model, stages with a Classifier their own.
+type CascadeClassifier struct {
+	stages      []CascadeStage
+	classifiers []*TaoClassifier
+	concurrency int
+}
+
+// NewCascadeClassifier creates a cascade of stages. Stages with a Provider share the prompts saved with
+// modelId, which may be empty when every stage has a Classifier. It returns a *ConfigError when there are no
+// stages, a stage has neither a provider nor a classifier or an invalid threshold, or the model can't be loaded.
+func NewCascadeClassifier(modelId string, stages []CascadeStage, opts ...CascadeOptions) (*CascadeClassifier, error) {
+	options := CascadeOptions{}
+
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if len(stages) == 0 {
+		return nil, &ConfigError{Field: "Stages", Err: fmt.Errorf("a cascade needs at least one stage")}
+	}
+
+	cascade := &CascadeClassifier{}
+
+	for index, stage := range stages {
+		if stage.Provider == nil && stage.Classifier == nil {
+			return nil, &ConfigError{Field: "Stages", Err: fmt.Errorf("stage %d has no provider or classifier", index+1)}
+		}
+
+		if stage.Threshold < 0 || stage.Threshold > 1 {
+			return nil, &ConfigError{Field: "Stages", Err: fmt.Errorf("stage %d threshold must be between 0 and 1", index+1)}
+		}
+
+		if stage.Name == "" {
+			stage.Name = fmt.Sprintf("stage %d", index+1)
+		}
+
+		if stage.Classifier != nil {
+			cascade.stages = append(cascade.stages, stage)
+			cascade.classifiers = append(cascade.classifiers, stage.Classifier)
+			cascade.concurrency = max(cascade.concurrency, stage.Classifier.concurrency)
+			continue
+		}
+
+		if modelId == "" {
+			return nil, &ConfigError{Field: "ModelId", Err: fmt.Errorf("modelId cannot be empty when stage %d has a provider", index+1)}
+		}
+
+		classifierOptions := options.Classifier
+		classifierOptions.ModelId = modelId
+		classifierOptions.Provider = stage.Provider
+
+		classifier, err := NewTaoClassifier(classifierOptions)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := classifier.LoadModel(modelId); err != nil {
+			return nil, &ConfigError{Field: "ModelId", Err: err}
+		}
+
+		// a saved calibration was fitted against a single backend
+		classifier.calibration = nil
+
+		cascade.stages = append(cascade.stages, stage)
+		cascade.classifiers = append(cascade.classifiers, classifier)
+		cascade.concurrency = max(cascade.concurrency, classifier.concurrency)
+	}
+
+	return cascade, nil
+}
+
+// Stages returns the stages in the order they are tried.
+func (cc *CascadeClassifier) Stages() []CascadeStage {
+	return append([]CascadeStage{}, cc.stages...)
+}
+
+func (cc *CascadeClassifier) PredictOne(text string, opts ...PredictOptions) (ClassificationResult, error) {
+	return cc.PredictOneContext(context.Background(), text, opts...)
+}
+
+// PredictOneContext returns the prediction of the first stage that is confident enough, or of the last stage.
+// When the last stage fails, the most confident result of an earlier stage is returned instead of the error.
+// Stage is set to the name of the stage that decided, and only that stage explains the prediction when
+// IncludeRationale is set.
+func (cc *CascadeClassifier) PredictOneContext(ctx context.Context, text string, opts ...PredictOptions) (ClassificationResult, error) {
+	options := PredictOptions{}
+
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	var result ClassificationResult
+	var err error
+	var decider *TaoClassifier
+
+	best := -1 // index of the most confident earlier result, -1 when every stage failed
+	results := make([]ClassificationResult, len(cc.classifiers))
+
+	for index, classifier := range cc.classifiers {
+		stage := cc.stages[index]
+		result, err = classifier.PredictOneContext(ctx, text)
+		result.Stage = stage.Name
+		decider = classifier
+
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return result, ctxErr
+		}
+
+		if errors.Is(err, ErrEmptyInput) {
+			return result, err
+		}
+
+		last := index == len(cc.classifiers)-1
+
+		if last || (err == nil && !result.Abstained && result.Probability >= stage.Threshold) {
+			break
+		}
+
+		if classifier.verbose {
+			fmt.Printf("Cascade: escalating from %s (probability %v, error %v)\n", stage.Name, result.Probability, err)
+		}
+
+		if err == nil {
+			results[index] = result
+
+			if best == -1 || moreConfident(result, results[best]) {
+				best = index
+			}
+		}
+	}
+
+	if err != nil && best != -1 {
+		if decider.verbose {
+			fmt.Printf("Cascade: falling back to %s after %s failed: %v\n", cc.stages[best].Name, result.Stage, err)
+		}
+
+		result, err, decider = results[best], nil, cc.classifiers[best]
+	}
+
+	if err != nil || !options.IncludeRationale || result.Abstained {
+		return result, err
+	}
+
+	return decider.explainOrKeep(ctx, text, result), nil
+}
+
+// moreConfident reports whether a is a better fallback than b: a prediction over an abstention, then the
+// higher probability.
+func moreConfident(a ClassificationResult, b ClassificationResult) bool {
+	if a.Abstained != b.Abstained {
+		return !a.Abstained
+	}
+
+	return a.Probability > b.Probability
+}
+
+// PredictMany classifies every text with the cascade. Failures of individual inputs are reported per item
+// in the BatchResult, the returned error is only set for invalid arguments or a done context.
+func (cc *CascadeClassifier) PredictMany(texts []string) (BatchResult, error) {
+	return cc.PredictManyContext(context.Background(), texts)
+}
+
+func (cc *CascadeClassifier) PredictManyContext(ctx context.Context, texts []string) (BatchResult, error) {
+	if len(texts) == 0 {
+		return BatchResult{}, fmt.Errorf("texts cannot be empty")
+	}
+
+	return predictConcurrently(ctx, len(texts), cc.concurrency, func(ctx context.Context, index int) (ClassificationResult, error) {
+		return cc.PredictOneContext(ctx, texts[index])
+	})
+}
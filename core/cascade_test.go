@@ -0,0 +1,230 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCascadeClassifier(t *testing.T) {
+	classifier := newTestClassifier(t, TaoClassifierOptions{ModelId: "test_cascade_model"})
+
+	classifier.PromptTrain(map[Label][]LabelDescription{
+		"positive": {"positive sentiment"},
+		"negative": {"negative sentiment"},
+	})
+
+	if _, err := classifier.SaveModel(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	newStages := func() (*FakeProvider, *FakeProvider, []CascadeStage) {
+		small := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			switch {
+			case strings.Contains(request.LastUserMessage(), "Meh"):
+				return `{"predicted_class": "positive", "probability": 0.55}`, nil
+			case strings.Contains(request.LastUserMessage(), "Huh"):
+				return `{"predicted_class": "confused", "probability": 0.9}`, nil
+			}
+
+			return `{"predicted_class": "positive", "probability": 0.95}`, nil
+		})
+
+		large := NewFakeProvider(
+			`{"predicted_class": "negative", "probability": 0.8}`,
+			`{"predicted_class": "negative", "probability": 0.7}`,
+		)
+
+		return small, large, []CascadeStage{
+			{Name: "small", Provider: small, Threshold: 0.8},
+			{Name: "large", Provider: large},
+		}
+	}
+
+	t.Run("Decides confident predictions in the first stage. ", func(t *testing.T) {
+		small, large, stages := newStages()
+
+		cascade, err := NewCascadeClassifier("test_cascade_model", stages)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		result, err := cascade.PredictOne("Great game!")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.PredictedClass != "positive" || result.Stage != "small" {
+			t.Errorf("Expected positive from the small stage, got %v from %v", result.PredictedClass, result.Stage)
+		}
+
+		if len(small.Requests()) != 1 || len(large.Requests()) != 0 {
+			t.Errorf("Expected only the small stage to be called, got %v and %v requests", len(small.Requests()), len(large.Requests()))
+		}
+
+		if !strings.Contains(small.Requests()[0].SystemPrompt(), "positive sentiment") {
+			t.Errorf("Expected the stage to use the saved prompts, got %v", small.Requests()[0].SystemPrompt())
+		}
+	})
+
+	t.Run("Escalates uncertain and failed predictions. ", func(t *testing.T) {
+		_, large, stages := newStages()
+
+		cascade, err := NewCascadeClassifier("test_cascade_model", stages)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		batch, err := cascade.PredictMany([]string{"Meh", "Huh"})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		for _, result := range batch.Results() {
+			if result.PredictedClass != "negative" || result.Stage != "large" {
+				t.Errorf("Expected negative from the large stage, got %v from %v", result.PredictedClass, result.Stage)
+			}
+		}
+
+		if len(large.Requests()) != 2 || !strings.Contains(large.Requests()[0].SystemPrompt(), "negative sentiment") {
+			t.Errorf("Expected both inputs to escalate to the large stage with the saved prompts, got %v", large.Requests())
+		}
+	})
+
+	t.Run("Returns the last stage's prediction even below its threshold. ", func(t *testing.T) {
+		small, _, _ := newStages()
+
+		cascade, err := NewCascadeClassifier("test_cascade_model", []CascadeStage{{Provider: small, Threshold: 0.99}})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		result, err := cascade.PredictOne("Meh")
+
+		if err != nil || result.Probability != 0.55 || result.Stage != "stage 1" {
+			t.Errorf("Expected the stage 1 prediction with probability 0.55, got %v from %v (%v)", result.Probability, result.Stage, err)
+		}
+	})
+
+	t.Run("Falls back to an earlier stage when the last stage fails. ", func(t *testing.T) {
+		small, _, _ := newStages()
+
+		explainer := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			if strings.Contains(request.SystemPrompt(), "explains classification decisions") {
+				return `{"rationale": "Mildly positive.", "influential_descriptions": [2]}`, nil
+			}
+
+			response, err := small.Chat(context.Background(), request)
+
+			return response.Content, err
+		})
+
+		failing := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			return "", errors.New("model is overloaded")
+		})
+
+		cascade, err := NewCascadeClassifier("test_cascade_model", []CascadeStage{
+			{Name: "small", Provider: explainer, Threshold: 0.8},
+			{Name: "large", Provider: failing},
+		})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		result, err := cascade.PredictOne("Meh", PredictOptions{IncludeRationale: true})
+
+		if err != nil || result.PredictedClass != "positive" || result.Stage != "small" {
+			t.Fatalf("Expected positive from the small stage, got %v from %v (%v)", result.PredictedClass, result.Stage, err)
+		}
+
+		if result.Rationale != "Mildly positive." || len(explainer.Requests()) != 2 {
+			t.Errorf("Expected the small stage to explain its prediction, got %q after %v requests", result.Rationale, len(explainer.Requests()))
+		}
+	})
+
+	t.Run("Only the deciding stage explains the prediction. ", func(t *testing.T) {
+		small, _, _ := newStages()
+
+		large := NewFakeProvider(
+			`{"predicted_class": "negative", "probability": 0.8}`,
+			`{"rationale": "Sounds disappointed.", "influential_descriptions": [1]}`,
+		)
+
+		cascade, err := NewCascadeClassifier("test_cascade_model", []CascadeStage{
+			{Name: "small", Provider: small, Threshold: 0.8},
+			{Name: "large", Provider: large},
+		})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		result, err := cascade.PredictOne("Meh", PredictOptions{IncludeRationale: true})
+
+		if err != nil || result.Stage != "large" || result.Rationale != "Sounds disappointed." {
+			t.Errorf("Expected the large stage to decide and explain, got %v from %v (%v)", result.Rationale, result.Stage, err)
+		}
+
+		if len(small.Requests()) != 1 || len(large.Requests()) != 2 {
+			t.Errorf("Expected no explanation from the small stage, got %v and %v requests", len(small.Requests()), len(large.Requests()))
+		}
+	})
+
+	t.Run("Uses the classifiers given to the stages. ", func(t *testing.T) {
+		small := newTestClassifier(t, TaoClassifierOptions{Provider: NewFakeProvider(`{"predicted_class": "positive", "probability": 0.6}`)})
+		small.PromptTrain(map[Label][]LabelDescription{"positive": {"happy"}, "negative": {"sad"}})
+
+		large := newTestClassifier(t, TaoClassifierOptions{Provider: NewFakeProvider(`{"predicted_class": "negative", "probability": 0.9}`), Temperature: 0.1})
+		large.PromptTrain(map[Label][]LabelDescription{"positive": {"glad"}, "negative": {"upset"}})
+
+		cascade, err := NewCascadeClassifier("", []CascadeStage{
+			{Name: "small", Classifier: small, Threshold: 0.8},
+			{Name: "large", Classifier: large},
+		})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		result, err := cascade.PredictOne("Meh")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.PredictedClass != "negative" || result.Stage != "large" {
+			t.Errorf("Expected negative from the large stage, got %v from %v", result.PredictedClass, result.Stage)
+		}
+	})
+
+	t.Run("Rejects invalid stages. ", func(t *testing.T) {
+		var configErr *ConfigError
+
+		if _, err := NewCascadeClassifier("test_cascade_model", nil); !errors.As(err, &configErr) {
+			t.Errorf("Expected a *ConfigError without stages, got %v", err)
+		}
+
+		if _, err := NewCascadeClassifier("test_cascade_model", []CascadeStage{{Provider: NewFakeProvider(), Threshold: 2}}); !errors.As(err, &configErr) {
+			t.Errorf("Expected a *ConfigError for an invalid threshold, got %v", err)
+		}
+
+		if _, err := NewCascadeClassifier("missing_cascade_model", []CascadeStage{{Provider: NewFakeProvider()}}); !errors.As(err, &configErr) || configErr.Field != "ModelId" {
+			t.Errorf("Expected a ModelId *ConfigError for a missing model, got %v", err)
+		}
+
+		if _, err := NewCascadeClassifier("", []CascadeStage{{Provider: NewFakeProvider()}}); !errors.As(err, &configErr) || configErr.Field != "ModelId" {
+			t.Errorf("Expected a ModelId *ConfigError for a provider stage without a model, got %v", err)
+		}
+
+		if _, err := NewCascadeClassifier("test_cascade_model", []CascadeStage{{Name: "empty"}}); !errors.As(err, &configErr) {
+			t.Errorf("Expected a *ConfigError for a stage without a provider or classifier, got %v", err)
+		}
+	})
+}
@@ -1,9 +1,10 @@
 package core
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,21 +16,76 @@ type Label = string
 type LabelDescription = string
 
 type TaoClassifier struct {
-	modelId          string
-	prompts          map[Label][]LabelDescription // mapping between label -> array of descriptions of label (prompts)
-	ai               *AI
-	config           *TaoConfig
-	dataset          []RowItem
-	targetColumn     string
-	temperature      float64
-	promptSampleSize int
-	verbose          bool
+	modelId                string
+	prompts                map[Label][]LabelDescription // mapping between label -> array of descriptions of label (prompts)
+	ai                     *AI
+	config                 *TaoConfig
+	dataset                []RowItem
+	targetColumn           string
+	temperature            float64
+	promptSampleSize       int
+	concurrency            int
+	batchSize              int
+	contextWindow          int
+	maxOutputTokens        int
+	labelPolicy            LabelPolicy
+	unknownLabel           Label
+	maxLabelRetries        int
+	scoringMode            ScoringMode
+	multiLabel             bool
+	multiLabelThreshold    float64
+	labelSeparator         string
+	minConfidence          float64
+	outOfDistributionCheck bool
+	calibration            *Calibration
+	selfConsistencySamples int
+	selfConsistencyStop    int
+	contrastiveTraining    bool
+	onTrainProgress        func(TrainProgress)
+	samplingStrategy       SamplingStrategy
+	maxTrainingRows        int
+	maxTrainingCalls       int
+	refineProfiles         bool
+	refineRounds           int
+	pairwiseRules          bool
+	decisionRules          []DecisionRule
+	examples               []Example
+	representativeOrder    map[Label][]int // indexes into examples per class, most representative first
+	fewShotK               int
+	fewShotStrategy        FewShotStrategy
+	examplesPerClass       int
+	verbose                bool
 }
 
 type ClassificationResult struct {
-	Label          Label       `json:"label"`
-	PredictedClass interface{} `json:"predicted_class"` // since numerical classes throw an error when unmarshalling if it's a number
-	Probability    float64     `json:"probability"`
+	Label          Label             `json:"label"`
+	PredictedClass interface{}       `json:"predicted_class"` // since numerical classes throw an error when unmarshalling if it's a number
+	Probability    float64           `json:"probability"`
+	Distribution   map[Label]float64 `json:"distribution,omitempty"`    // probability of every label, sums to 1
+	RawProbability float64           `json:"raw_probability,omitempty"` // Probability before calibration, set when the model is calibrated
+
+	// multi-label mode only
+	PredictedLabels    []Label           `json:"predicted_labels,omitempty"`    // labels at or above the threshold, most likely first
+	LabelProbabilities map[Label]float64 `json:"label_probabilities,omitempty"` // independent probability of every label
+
+	// set instead of a predicted class when the classifier isn't confident enough or the input fits no label
+	Abstained     bool          `json:"abstained,omitempty"`
+	AbstainReason AbstainReason `json:"abstain_reason,omitempty"`
+
+	// self-consistency only, number of samples that voted for each label
+	Votes map[Label]int `json:"votes,omitempty"`
+
+	// CascadeClassifier only, name of the stage that decided
+	Stage string `json:"stage,omitempty"`
+
+	// set with PredictOptions.IncludeRationale
+	Rationale               string                   `json:"rationale,omitempty"`
+	InfluentialDescriptions []InfluentialDescription `json:"influential_descriptions,omitempty"`
+}
+
+// requiredJsonKeys are the keys of a prediction in the model output, the other fields are optional or set later.
+func (ClassificationResult) requiredJsonKeys() []string {
+	return []string{"predicted_class", "probability"}
 }
 
 type ClassifierProfile struct {
@@ -37,24 +93,65 @@ type ClassifierProfile struct {
 	Description []string `json:"description"`
 }
 
+func (ClassifierProfile) requiredJsonKeys() []string {
+	return []string{"label", "description"}
+}
+
 type TaoClassifierOptions struct {
-	ModelId             string
-	TrainingDatasetPath string
-	TargetColumn        string
-	Temperature         float64
-	PromptSampleSize    int
-	Verbose             bool
+	ModelId                string
+	TrainingDatasetPath    string
+	TargetColumn           string
+	Temperature            float64
+	PromptSampleSize       int
+	Verbose                bool
+	Provider               Provider            // LLM backend used for training and prediction, defaults to OpenAI
+	RetryPolicy            *RetryPolicy        // retries for failed provider calls, defaults to DefaultRetryPolicy()
+	RateLimit              RateLimit           // client-side requests/tokens per minute limit, unlimited by default
+	Concurrency            int                 // number of parallel workers for the PredictMany* methods, defaults to 1
+	BatchSize              int                 // max inputs packed into one prompt by the PredictMany* methods, 1 (default) disables batching
+	ContextWindow          int                 // model context window in tokens used to size batches, defaults to 128000
+	MaxOutputTokens        int                 // max response tokens of the model used to size batches, defaults to 4096
+	LabelPolicy            LabelPolicy         // what to do when the model predicts an unknown class, defaults to LabelPolicyReject
+	UnknownLabel           Label               // class reported for unknown predictions with LabelPolicyUnknown, defaults to "unknown"
+	MaxLabelRetries        int                 // corrective follow-ups with LabelPolicyRetry, defaults to 1
+	ScoringMode            ScoringMode         // how probabilities are computed, defaults to ScoringSelfReported
+	MultiLabel             bool                // predict any number of labels per input instead of exactly one
+	MultiLabelThreshold    float64             // minimum probability for a label to be predicted in multi-label mode, defaults to 0.5
+	LabelSeparator         string              // separates the labels of a row in the target column in multi-label mode, defaults to "|"
+	MinConfidence          float64             // abstain from predictions with a lower probability, 0 (default) never abstains
+	OutOfDistributionCheck bool                // ask the model whether each input fits any label and abstain if not, costs one more call per input
+	SelfConsistency        int                 // samples drawn per prediction and majority voted, 0 or 1 (default) draws a single prediction
+	SelfConsistencyStop    int                 // stop sampling when the first SelfConsistencyStop samples agree, 0 (default) draws all samples
+	ContrastiveTraining    bool                // show rows of other classes when profiling each class during Train
+	OnTrainProgress        func(TrainProgress) // called by Train when a class is done
+	SamplingStrategy       SamplingStrategy    // order in which Train uses the dataset rows, defaults to SamplingStratified
+	MaxTrainingRows        int                 // distinct rows Train sends to the model, 0 (default) is unlimited
+	MaxTrainingCalls       int                 // profile calls Train makes, 0 (default) is unlimited
+	RefineProfiles         bool                // let Train rewrite each class's profile from batches of rows instead of appending descriptions
+	RefineRounds           int                 // refinement calls per class with RefineProfiles, defaults to 3
+	PairwiseRules          bool                // let Train also generate "X vs Y" decision rules from rows of every pair of classes
+	FewShotK               int                 // labeled examples shown in prediction prompts, 0 (default) shows none
+	FewShotStrategy        FewShotStrategy     // which examples are shown, defaults to FewShotRandom
+	ExamplesPerClass       int                 // examples Train stores per class from the dataset when FewShotK > 0, defaults to 20
 }
 
 type SavedTaoModel struct {
-	ModelId          string
-	Prompts          map[Label][]LabelDescription
-	Temperature      float64
-	PromptSampleSize int
-	TargetColumn     string
+	ModelId             string
+	Prompts             map[Label][]LabelDescription
+	Temperature         float64
+	PromptSampleSize    int
+	TargetColumn        string
+	MultiLabel          bool           `json:",omitempty"`
+	MultiLabelThreshold float64        `json:",omitempty"`
+	LabelSeparator      string         `json:",omitempty"`
+	Calibration         *Calibration   `json:",omitempty"`
+	DecisionRules       []DecisionRule `json:",omitempty"`
+	Examples            []Example      `json:",omitempty"`
 }
 
-func NewTaoClassifier(opts ...TaoClassifierOptions) *TaoClassifier {
+// NewTaoClassifier creates a classifier. It returns a *ConfigError when the options are invalid,
+// the training dataset can't be read or no provider can be configured.
+func NewTaoClassifier(opts ...TaoClassifierOptions) (*TaoClassifier, error) {
 	defaultModelId := fmt.Sprint(time.Now().Unix())
 
 	options := TaoClassifierOptions{
@@ -74,6 +171,106 @@ func NewTaoClassifier(opts ...TaoClassifierOptions) *TaoClassifier {
 		options.PromptSampleSize = 10
 	}
 
+	if options.Concurrency <= 0 {
+		options.Concurrency = 1
+	}
+
+	if options.BatchSize <= 0 {
+		options.BatchSize = 1
+	}
+
+	if options.ContextWindow <= 0 {
+		options.ContextWindow = defaultContextWindow
+	}
+
+	if options.MaxOutputTokens <= 0 {
+		options.MaxOutputTokens = defaultMaxOutputTokens
+	}
+
+	if options.LabelPolicy == "" {
+		options.LabelPolicy = LabelPolicyReject
+	}
+
+	switch options.LabelPolicy {
+	case LabelPolicyReject, LabelPolicyRetry, LabelPolicyUnknown:
+	default:
+		return nil, &ConfigError{Field: "LabelPolicy", Err: fmt.Errorf("unknown label policy %q", options.LabelPolicy)}
+	}
+
+	if options.UnknownLabel == "" {
+		options.UnknownLabel = defaultUnknownLabel
+	}
+
+	if options.MaxLabelRetries <= 0 {
+		options.MaxLabelRetries = defaultMaxLabelRetries
+	}
+
+	if options.ScoringMode == "" {
+		options.ScoringMode = ScoringSelfReported
+	}
+
+	switch options.ScoringMode {
+	case ScoringSelfReported, ScoringLogprobs:
+	default:
+		return nil, &ConfigError{Field: "ScoringMode", Err: fmt.Errorf("unknown scoring mode %q", options.ScoringMode)}
+	}
+
+	if options.MultiLabelThreshold <= 0 || options.MultiLabelThreshold > 1 {
+		options.MultiLabelThreshold = defaultMultiLabelThreshold
+	}
+
+	if options.LabelSeparator == "" {
+		options.LabelSeparator = defaultLabelSeparator
+	}
+
+	if options.RefineRounds <= 0 {
+		options.RefineRounds = defaultRefineRounds
+	}
+
+	if options.FewShotStrategy == "" {
+		options.FewShotStrategy = FewShotRandom
+	}
+
+	switch options.FewShotStrategy {
+	case FewShotRandom, FewShotNearest, FewShotRepresentative:
+	default:
+		return nil, &ConfigError{Field: "FewShotStrategy", Err: fmt.Errorf("unknown few-shot strategy %q", options.FewShotStrategy)}
+	}
+
+	if options.ExamplesPerClass <= 0 {
+		options.ExamplesPerClass = defaultExamplesPerClass
+	}
+
+	if options.SamplingStrategy == "" {
+		options.SamplingStrategy = SamplingStratified
+	}
+
+	switch options.SamplingStrategy {
+	case SamplingStratified, SamplingShuffle, SamplingDiversity:
+	default:
+		return nil, &ConfigError{Field: "SamplingStrategy", Err: fmt.Errorf("unknown sampling strategy %q", options.SamplingStrategy)}
+	}
+
+	if options.MaxTrainingRows < 0 {
+		return nil, &ConfigError{Field: "MaxTrainingRows", Err: fmt.Errorf("MaxTrainingRows cannot be negative")}
+	}
+
+	if options.MaxTrainingCalls < 0 {
+		return nil, &ConfigError{Field: "MaxTrainingCalls", Err: fmt.Errorf("MaxTrainingCalls cannot be negative")}
+	}
+
+	if options.MultiLabel && options.ScoringMode == ScoringLogprobs {
+		return nil, &ConfigError{Field: "ScoringMode", Err: fmt.Errorf("logprob scoring is not supported in multi-label mode")}
+	}
+
+	if options.SelfConsistency > 1 && (options.MultiLabel || options.ScoringMode == ScoringLogprobs) {
+		return nil, &ConfigError{Field: "SelfConsistency", Err: fmt.Errorf("self-consistency is not supported with multi-label or logprob scoring")}
+	}
+
+	if options.SelfConsistencyStop < 0 || options.SelfConsistencyStop > options.SelfConsistency {
+		return nil, &ConfigError{Field: "SelfConsistencyStop", Err: fmt.Errorf("SelfConsistencyStop must be between 0 and SelfConsistency")}
+	}
+
 	if options.ModelId == "" {
 		if options.Verbose {
 			fmt.Println("ModelId not provided, using autogenerating ID: ", defaultModelId)
@@ -90,59 +287,92 @@ func NewTaoClassifier(opts ...TaoClassifierOptions) *TaoClassifier {
 		}
 	}
 
-	ai := NewAI()
-
-	dataset := []RowItem{}
-
 	if options.TrainingDatasetPath != "" && options.TargetColumn == "" {
-		log.Fatal("NewTaoClassifier: TargetColumn cannot be empty when TrainingDatasetPath is specified. ")
-		panic("TargetColumn cannot be empty when TrainingDatasetPath is specified. ")
+		return nil, &ConfigError{Field: "TargetColumn", Err: fmt.Errorf("TargetColumn cannot be empty when TrainingDatasetPath is specified")}
 	}
 
-	prompts := make(map[Label][]LabelDescription)
+	provider := options.Provider
 
-	if options.TrainingDatasetPath != "" && options.TargetColumn != "" {
-		dataset, err = ReadCSVFile(options.TrainingDatasetPath)
+	if provider == nil {
+		openaiProvider, err := NewOpenAIProvider()
 
-		// extract classes from dataset based on target column
-		classes := ExtractClasses(dataset, options.TargetColumn)
+		if err != nil {
+			return nil, err
+		}
 
-		if len(classes) == 0 {
+		provider = openaiProvider
+	}
 
-			if options.Verbose {
-				fmt.Printf("NewTaoClassifier: no classes found on targetColumn=%s for dataset\n", options.TargetColumn)
-			}
-		}
+	ai := NewAIWithProvider(provider, AIOptions{RetryPolicy: options.RetryPolicy, RateLimit: options.RateLimit})
 
-		for _, class := range classes {
-			if _, ok := prompts[class]; !ok {
-				prompts[class] = []LabelDescription{}
-			}
-		}
+	dataset := []RowItem{}
+
+	prompts := make(map[Label][]LabelDescription)
+
+	if options.TrainingDatasetPath != "" && options.TargetColumn != "" {
+		dataset, err = ReadCSVFile(options.TrainingDatasetPath)
 
 		if err != nil {
-			log.Fatal("NewTaoClassifier: Failed to read training dataset", err)
-			panic("NewTaoClassifier: Failed to read training dataset")
+			return nil, &ConfigError{Field: "TrainingDatasetPath", Err: err}
 		}
 	}
 
 	config := GetTaoConfig()
 
-	return &TaoClassifier{
-		modelId:          options.ModelId,
-		prompts:          prompts,
-		ai:               ai,
-		dataset:          dataset,
-		temperature:      options.Temperature,
-		promptSampleSize: options.PromptSampleSize,
-		targetColumn:     options.TargetColumn,
-		verbose:          options.Verbose,
-		config:           config,
+	if config == nil {
+		return nil, &ConfigError{Field: "TaoConfig", Err: fmt.Errorf("failed to initialize config folder")}
 	}
+
+	classifier := &TaoClassifier{
+		modelId:                options.ModelId,
+		prompts:                prompts,
+		ai:                     ai,
+		dataset:                dataset,
+		temperature:            options.Temperature,
+		promptSampleSize:       options.PromptSampleSize,
+		targetColumn:           options.TargetColumn,
+		concurrency:            options.Concurrency,
+		batchSize:              options.BatchSize,
+		contextWindow:          options.ContextWindow,
+		maxOutputTokens:        options.MaxOutputTokens,
+		labelPolicy:            options.LabelPolicy,
+		unknownLabel:           options.UnknownLabel,
+		maxLabelRetries:        options.MaxLabelRetries,
+		scoringMode:            options.ScoringMode,
+		multiLabel:             options.MultiLabel,
+		multiLabelThreshold:    options.MultiLabelThreshold,
+		labelSeparator:         options.LabelSeparator,
+		minConfidence:          options.MinConfidence,
+		outOfDistributionCheck: options.OutOfDistributionCheck,
+		selfConsistencySamples: options.SelfConsistency,
+		selfConsistencyStop:    options.SelfConsistencyStop,
+		contrastiveTraining:    options.ContrastiveTraining,
+		onTrainProgress:        options.OnTrainProgress,
+		samplingStrategy:       options.SamplingStrategy,
+		maxTrainingRows:        options.MaxTrainingRows,
+		maxTrainingCalls:       options.MaxTrainingCalls,
+		refineProfiles:         options.RefineProfiles,
+		refineRounds:           options.RefineRounds,
+		pairwiseRules:          options.PairwiseRules,
+		fewShotK:               options.FewShotK,
+		fewShotStrategy:        options.FewShotStrategy,
+		examplesPerClass:       options.ExamplesPerClass,
+		verbose:                options.Verbose,
+		config:                 config,
+	}
+
+	// extract classes from dataset based on target column
+	classifier.initializePromptsFromDataset()
+
+	return classifier, nil
 }
 
 func (c *TaoClassifier) initializePromptsFromDataset() {
-	classes := ExtractClasses(c.dataset, c.targetColumn)
+	if len(c.dataset) == 0 || c.targetColumn == "" {
+		return
+	}
+
+	classes := c.datasetClasses()
 
 	if len(classes) == 0 {
 
@@ -164,12 +394,22 @@ func (c *TaoClassifier) PromptTrain(prompts map[Label][]LabelDescription) (bool,
 	}
 
 	for label, descriptionList := range prompts {
+		if c.multiLabel {
+			// descriptions of a label combination such as "billing|bug" apply to each of its labels
+			for _, singleLabel := range splitLabels(label, c.labelSeparator) {
+				c.prompts[singleLabel] = append(c.prompts[singleLabel], descriptionList...)
+			}
+
+			continue
+		}
+
 		c.prompts[label] = append(c.prompts[label], descriptionList...)
 	}
 
 	return true, nil
 }
 
+// GetAvailableLabels returns the trained labels sorted, so prompts listing them are the same on every call.
 func (c *TaoClassifier) GetAvailableLabels() ([]Label, error) {
 	labels := []Label{}
 
@@ -177,25 +417,33 @@ func (c *TaoClassifier) GetAvailableLabels() ([]Label, error) {
 		labels = append(labels, label)
 	}
 
+	sort.Strings(labels)
+
 	return labels, nil
 }
 
 func (c *TaoClassifier) GenerateClassifierProfile(label Label, rowItem RowItem, currentClassifierProfile ClassifierProfile) (ClassifierProfile, error) {
+	return c.GenerateClassifierProfileContext(context.Background(), label, rowItem, currentClassifierProfile)
+}
+
+func (c *TaoClassifier) GenerateClassifierProfileContext(ctx context.Context, label Label, rowItem RowItem, currentClassifierProfile ClassifierProfile) (ClassifierProfile, error) {
 	if len(rowItem) == 0 {
 		return ClassifierProfile{}, fmt.Errorf("rowItem cannot be empty")
 	}
 
-	combinedRowItems := ""
+	return c.generateClassifierProfile(ctx, label, []RowItem{rowItem}, nil, currentClassifierProfile)
+}
 
-	for key, value := range rowItem {
-		combinedRowItems += fmt.Sprintf("%s: %s\n", key, value)
-	}
+// generateClassifierProfile describes label from rows that belong to it. When contrast has rows of other
+// labels, the model is asked what sets label apart from them. When current has descriptions, the model
+// rewrites them with what the rows show and the result is the complete, consolidated profile.
+func (c *TaoClassifier) generateClassifierProfile(ctx context.Context, label Label, rows []RowItem, contrast []RowItem, current ClassifierProfile) (ClassifierProfile, error) {
+	combinedRowItems := formatRowItems(rows)
 
 	availableLabels, err := c.GetAvailableLabels()
 
 	if err != nil {
-		log.Fatal("GenerateClassifierProfile: Failed to get available labels", err)
-		return ClassifierProfile{}, err
+		return ClassifierProfile{}, fmt.Errorf("GenerateClassifierProfile: failed to get available labels: %w", err)
 	}
 
 	labelsStr := strings.Join(availableLabels, ", ")
@@ -209,9 +457,21 @@ func (c *TaoClassifier) GenerateClassifierProfile(label Label, rowItem RowItem,
 					Based on the label, identify features within the row items that are relevant to the label.
 					Target Column for Classification: ` + c.targetColumn + "\nAvailable Labels: " + labelsStr
 
+	if c.multiLabel {
+		systemPrompt += fmt.Sprintf("\nThis is a multi-label task: a row can belong to several labels, listed in the target column separated by %q. Describe what indicates the given label regardless of the other labels of the row.", c.labelSeparator)
+	}
+
 	userPrompt := fmt.Sprintf(`Generate a classification profile for the label %s given the following row items: %s`, label, combinedRowItems)
 
-	text, err := c.ai.GenerateText(userPrompt, GenerateTextOptions{Verbose: false, System: systemPrompt})
+	if len(contrast) > 0 {
+		userPrompt += fmt.Sprintf("\nFor contrast, the following row items belong to other labels. Focus on what sets the label %s apart from them: %s", label, formatRowItems(contrast))
+	}
+
+	if len(current.Description) > 0 {
+		userPrompt += fmt.Sprintf("\nThe current classification profile of the label %s is:\n- %s\nUpdate it with what the row items show: keep what still holds, rewrite what they contradict, merge duplicates and return the complete profile with at most %d descriptions.", label, strings.Join(current.Description, "\n- "), c.promptSampleSize)
+	}
+
+	text, err := c.ai.GenerateTextContext(ctx, userPrompt, GenerateTextOptions{Verbose: false, System: systemPrompt, ResponseSchema: profileSchema()})
 
 	if c.verbose {
 		fmt.Println("System Prompt: ", systemPrompt)
@@ -220,78 +480,201 @@ func (c *TaoClassifier) GenerateClassifierProfile(label Label, rowItem RowItem,
 	}
 
 	if err != nil {
-		log.Fatal("GenerateClassifierProfile: Failed to generate completions", err)
-		return ClassifierProfile{}, err
+		return ClassifierProfile{}, fmt.Errorf("GenerateClassifierProfile: failed to generate completions: %w", err)
 	}
 
 	result, err := CleanGPTJson[ClassifierProfile](text)
 
 	if err != nil {
-		log.Fatal("GenerateClassifierProfile: Failed to generate completions", err)
-		return ClassifierProfile{}, err
+		return ClassifierProfile{}, fmt.Errorf("GenerateClassifierProfile: failed to parse classifier profile: %w", err)
+	}
+
+	if len(current.Description) > 0 {
+		result.Description = consolidateDescriptions(result.Description, c.promptSampleSize)
 	}
 
 	return result, nil
 }
 
 func (c *TaoClassifier) Train() error {
-	maxDescriptions := c.promptSampleSize
-	selectedRows := make(map[int]bool)
+	return c.TrainContext(context.Background())
+}
 
-	for index := range len(c.dataset) {
-		selectedRows[index] = false
-	}
+// TrainContext is like Train but stops with ctx.Err() once ctx is cancelled or its deadline passes.
+func (c *TaoClassifier) TrainContext(ctx context.Context) error {
+	maxDescriptions := c.promptSampleSize
 
 	if c.verbose {
-		fmt.Printf("selectedRows: %+v\n", selectedRows)
 		fmt.Println("Dataset Size: ", len(c.dataset))
 		fmt.Println("Prompts Before: ", c.prompts)
 	}
 
 	c.initializePromptsFromDataset()
 
-	for {
-		row, index := SelectRandomRow(c.dataset)
-		selectedRowsCount := CountSelectedRows(c.dataset, selectedRows)
+	if c.fewShotK > 0 && c.targetColumn != "" {
+		if err := c.addDatasetExamples(); err != nil {
+			return err
+		}
+	}
 
-		if selectedRowsCount == len(c.dataset) {
-			if c.verbose {
-				fmt.Println("All rows have been selected. ")
-			}
+	groups := c.rowsByClass()
+	classes := []Label{}
+
+	for class := range c.prompts {
+		classes = append(classes, class)
+	}
+
+	sort.Strings(classes)
+
+	// every class is profiled only from rows that belong to it, in the order of the sampling strategy
+	plan := c.samplingPlan(groups, classes)
+	remaining := map[Label]int{}
+
+	for _, sample := range plan {
+		remaining[sample.class]++
+	}
+
+	usedRows := map[Label]int{}
+	selectedRows := map[int]bool{}
+	pending := map[Label][]RowItem{}
+	rounds := map[Label]int{}
+	done := map[Label]bool{}
+	calls := 0
+
+	// with RefineProfiles every call rewrites the profile of the class from a batch of rows, otherwise every
+	// row adds descriptions
+	batchRows := c.trainingBatchRows()
+
+	finish := func(class Label) {
+		done[class] = true
+
+		if c.onTrainProgress != nil {
+			c.onTrainProgress(TrainProgress{
+				Class:        class,
+				ClassIndex:   len(done),
+				Classes:      len(classes),
+				Rows:         usedRows[class],
+				Descriptions: len(c.prompts[class]),
+				Calls:        calls,
+			})
+		}
+	}
+
+	isFull := func(class Label) bool {
+		if c.refineProfiles {
+			return rounds[class] >= c.refineRounds
+		}
+
+		return len(c.prompts[class]) >= maxDescriptions
+	}
+
+	profile := func(class Label) error {
+		rows := pending[class]
+		delete(pending, class)
+
+		contrast := []RowItem{}
+
+		if c.contrastiveTraining {
+			contrast = c.contrastRows(groups, class, selectedRows)
+		}
+
+		current := ClassifierProfile{}
+
+		if c.refineProfiles {
+			current = ClassifierProfile{Label: class, Description: c.prompts[class]}
+		}
+
+		classificationProfile, err := c.generateClassifierProfile(ctx, class, rows, contrast, current)
+		calls++
+
+		if c.verbose {
+			fmt.Println("Classification Profile: ", classificationProfile)
+		}
+
+		if err != nil {
+			return fmt.Errorf("Train: failed to generate classifier profile for class %s: %w", class, err)
+		}
+
+		usedRows[class] += len(rows)
+		rounds[class]++
+
+		if c.refineProfiles {
+			c.prompts[class] = consolidateDescriptions(classificationProfile.Description, maxDescriptions)
+		} else {
+			c.prompts[class] = append(c.prompts[class], classificationProfile.Description...)
+		}
+
+		return nil
+	}
+
+	for _, class := range classes {
+		if (!c.refineProfiles && isFull(class)) || remaining[class] == 0 {
+			finish(class)
+		}
+	}
+
+	for _, sample := range plan {
+		if len(done) == len(classes) {
 			break
 		}
 
-		if selectedRows[index] {
+		if done[sample.class] {
+			continue
+		}
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if c.maxTrainingCalls > 0 && calls >= c.maxTrainingCalls {
 			if c.verbose {
-				fmt.Println("Row already selected. ", selectedRows[index])
+				fmt.Println("Train: reached MaxTrainingCalls", c.maxTrainingCalls)
 			}
+			break
+		}
 
-			continue
+		remaining[sample.class]--
+
+		// once the row budget is used up, only rows selected before can still profile other classes
+		if c.maxTrainingRows == 0 || selectedRows[sample.row] || len(selectedRows) < c.maxTrainingRows {
+			selectedRows[sample.row] = true
+			pending[sample.class] = append(pending[sample.class], c.dataset[sample.row])
 		}
 
-		for class, descriptionList := range c.prompts {
-			// if label already has enough prompts, skip to the next label
-			if len(descriptionList) >= maxDescriptions {
-				if c.verbose {
-					fmt.Println("Label already has enough prompts. ", class, len(descriptionList), maxDescriptions)
-				}
-				continue
+		if len(pending[sample.class]) > 0 && (len(pending[sample.class]) >= batchRows || remaining[sample.class] == 0) {
+			if err := profile(sample.class); err != nil {
+				return err
 			}
+		}
 
-			classificationProfile, err := c.GenerateClassifierProfile(class, row, ClassifierProfile{})
+		if isFull(sample.class) || remaining[sample.class] == 0 {
+			finish(sample.class)
+		}
+	}
 
-			if c.verbose {
-				fmt.Println("Classification Profile: ", classificationProfile)
-			}
+	// a budget can stop training before every class is done
+	for _, class := range classes {
+		if !done[class] {
+			finish(class)
+		}
+	}
 
-			if err != nil {
-				log.Fatal("Train: Failed to generate classifier profile", err)
-			} else {
-				c.prompts[class] = append(descriptionList, classificationProfile.Description...)
-			}
+	for _, class := range classes {
+		if len(c.prompts[class]) == 0 {
+			return fmt.Errorf("Train: no descriptions were generated for class %s, raise MaxTrainingCalls or MaxTrainingRows or check the dataset", class)
+		}
+	}
+
+	if c.pairwiseRules {
+		budget := -1
+
+		if c.maxTrainingCalls > 0 {
+			budget = max(0, c.maxTrainingCalls-calls)
 		}
 
-		selectedRows[index] = true
+		if _, err := c.generateDecisionRules(ctx, groups, classes, selectedRows, budget); err != nil {
+			return err
+		}
 	}
 
 	if c.verbose {
@@ -327,13 +710,7 @@ func (c *TaoClassifier) SaveModel() (bool, error) {
 		return false, fmt.Errorf("prompts are not loaded. Call Train() or PromptTrain() first. ")
 	}
 
-	savedTaoModel := SavedTaoModel{
-		ModelId:          c.modelId,
-		Prompts:          c.prompts,
-		Temperature:      c.temperature,
-		PromptSampleSize: c.promptSampleSize,
-		TargetColumn:     c.targetColumn,
-	}
+	savedTaoModel := c.GetSavableModel()
 
 	modelId, err := c.config.SaveModelToFile(savedTaoModel, SaveModelToFileOptions{Overwrite: true})
 
@@ -370,6 +747,19 @@ func (c *TaoClassifier) LoadModel(modelId string) (bool, error) {
 	c.temperature = loadedModel.Temperature
 	c.promptSampleSize = loadedModel.PromptSampleSize
 	c.targetColumn = loadedModel.TargetColumn
+	c.multiLabel = loadedModel.MultiLabel
+	c.calibration = loadedModel.Calibration
+	c.decisionRules = loadedModel.DecisionRules
+	c.examples = loadedModel.Examples
+	c.updateRepresentativeOrder()
+
+	if loadedModel.MultiLabelThreshold > 0 {
+		c.multiLabelThreshold = loadedModel.MultiLabelThreshold
+	}
+
+	if loadedModel.LabelSeparator != "" {
+		c.labelSeparator = loadedModel.LabelSeparator
+	}
 
 	if c.verbose {
 		fmt.Println("LoadModel: model loaded successfully: modelId =", modelId)
@@ -426,137 +816,325 @@ func (c *TaoClassifier) RemovePrompt(label Label) (bool, error) {
 	}
 
 	delete(c.prompts, label)
+	c.removeDecisionRules(label)
+	c.removeExamples(label)
+
 	return true, nil
 }
 
+// ClearPrompts removes every label together with the decision rules and the few-shot examples.
 func (c *TaoClassifier) ClearPrompts() {
 	c.prompts = make(map[Label][]LabelDescription)
+	c.decisionRules = nil
+	c.ClearExamples()
 }
 
-func (c *TaoClassifier) PredictOne(text string) (ClassificationResult, error) {
-	// convert c.prompts to a string
+func (c *TaoClassifier) PredictOne(text string, opts ...PredictOptions) (ClassificationResult, error) {
+	return c.PredictOneContext(context.Background(), text, opts...)
+}
+
+// formatClassDescriptors converts c.prompts to the "Class->Description" context used in prediction prompts,
+// followed by the decision rules.
+func (c *TaoClassifier) formatClassDescriptors() string {
+	labels, _ := c.GetAvailableLabels()
+
 	classDescriptors := "Class->Description\n"
-	for className, descriptionList := range c.prompts {
-		for _, description := range descriptionList {
+	for _, className := range labels {
+		for _, description := range c.prompts[className] {
 			classDescriptors += fmt.Sprintf("%s: %s\n", className, description)
 		}
 	}
 
+	if rules := c.formatDecisionRules(); rules != "" {
+		classDescriptors += "\n" + rules
+	}
+
+	return classDescriptors
+}
+
+// PredictOneContext is like PredictOne but passes ctx down to the provider calls. The result is Abstained
+// instead of an error when MinConfidence or OutOfDistributionCheck reject the prediction. With
+// IncludeRationale a single self-reported prediction is explained in the same request, other scoring modes
+// and responses without a rationale in one more call. When only that call fails, the prediction is returned
+// without a rationale.
+func (c *TaoClassifier) PredictOneContext(ctx context.Context, text string, opts ...PredictOptions) (ClassificationResult, error) {
+	options := PredictOptions{}
+
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	result, err := c.predictOne(ctx, text, options.IncludeRationale)
+
+	if err != nil {
+		return result, err
+	}
+
+	result, err = c.applyAbstention(ctx, text, c.applyCalibration(result))
+
+	if err != nil || !options.IncludeRationale {
+		return result, err
+	}
+
+	if result.Abstained {
+		result.Rationale, result.InfluentialDescriptions = "", nil
+		return result, nil
+	}
+
+	if result.Rationale != "" {
+		return result, nil
+	}
+
+	return c.explainOrKeep(ctx, text, result), nil
+}
+
+func (c *TaoClassifier) predictOne(ctx context.Context, text string, explain bool) (ClassificationResult, error) {
+	if text == "" {
+		return ClassificationResult{Label: "", Probability: -1}, ErrEmptyInput
+	}
+
+	if c.multiLabel {
+		return c.predictMultiLabel(ctx, text)
+	}
+
+	if c.scoringMode == ScoringLogprobs {
+		return c.predictLogprobs(ctx, text)
+	}
+
+	if c.selfConsistencySamples > 1 {
+		return c.predictSelfConsistent(ctx, text)
+	}
+
+	return c.predictSample(ctx, text, GenerateTextOptions{}, explain)
+}
+
+// predictSample classifies text with one self-reported prediction, sampling.Temperature and sampling.Seed
+// are passed to the model. With explain the response also carries the rationale of the prediction.
+func (c *TaoClassifier) predictSample(ctx context.Context, text string, sampling GenerateTextOptions, explain bool) (ClassificationResult, error) {
+	classDescriptors := c.predictionContext(text)
+
 	// TODO: Implement OpenAI API call
 	systemPrompt := fmt.Sprintf(`You are an AI assistant that performs classification. 
 	You will be given a map of predicted classes and their corresponding descriptions. 
 	Use this information to classify the given data point.
-	Respond in JSON with { predicted_class: <class>, "probability": <probability> }. 
+	Respond in JSON with { predicted_class: <class>, "probability": <probability>, "distribution": { <class>: <probability> } }, with a probability for every class in the distribution. 
 	The label should be only from the given labels.
 	Context: %s\n`, classDescriptors)
 
-	if text == "" {
-		return ClassificationResult{Label: "", Probability: -1}, fmt.Errorf("text cannot be empty")
+	schema := c.classificationSchema()
+	descriptions := []InfluentialDescription{}
+
+	if explain {
+		var instructions string
+
+		instructions, descriptions = c.explanationInstructions()
+		systemPrompt += "\t" + instructions
+		schema = withExplanation(schema)
 	}
 
 	userPrompt := fmt.Sprintf(`Classify the following text: "%s"`, text)
+	messages := []Message{{Role: RoleUser, Content: userPrompt}}
 
-	text, err := c.ai.GenerateText(userPrompt, GenerateTextOptions{Verbose: false, System: systemPrompt})
+	for retries := 0; ; retries++ {
+		text, err := c.ai.GenerateChatContext(ctx, messages, GenerateTextOptions{Verbose: false, System: systemPrompt, ResponseSchema: schema, Temperature: sampling.Temperature, Seed: sampling.Seed})
 
-	if err != nil {
-		return ClassificationResult{Label: "", Probability: -1}, err
-	}
+		if err != nil {
+			return ClassificationResult{Label: "", Probability: -1}, err
+		}
 
-	if c.verbose {
-		fmt.Println("System Prompt: ", systemPrompt)
-		fmt.Println("Prompt: ", userPrompt)
-		fmt.Println("Generated Text: ", text)
-	}
+		if c.verbose {
+			fmt.Println("System Prompt: ", systemPrompt)
+			fmt.Println("Prompt: ", messages[len(messages)-1].Content)
+			fmt.Println("Generated Text: ", text)
+		}
 
-	result, err := CleanGPTJson[ClassificationResult](text)
+		var result ClassificationResult
 
-	if err != nil {
-		fmt.Println("PredictOne: failed to clean GPT JSON:", err)
-		return ClassificationResult{Label: "", Probability: -1}, err
-	}
+		if explain {
+			var explained explainedClassification
 
-	if c.targetColumn != "" {
-		result.Label = c.targetColumn
-	} else {
-		result.Label = ""
+			explained, err = CleanGPTJson[explainedClassification](text)
+			result = explained.ClassificationResult
+			result.InfluentialDescriptions = citedDescriptions(explained.InfluentialDescriptions, descriptions)
+		} else {
+			result, err = CleanGPTJson[ClassificationResult](text)
+		}
+
+		if err != nil {
+			if c.verbose {
+				fmt.Println("PredictOne: failed to clean GPT JSON:", err)
+			}
+
+			return ClassificationResult{Label: "", Probability: -1}, err
+		}
+
+		result, err = c.validateLabel(result, text)
+
+		if err != nil {
+			if c.labelPolicy == LabelPolicyRetry && retries < c.maxLabelRetries {
+				messages = append(messages,
+					Message{Role: RoleAssistant, Content: text},
+					Message{Role: RoleUser, Content: c.labelCorrection(result.PredictedClass)},
+				)
+
+				continue
+			}
+
+			return ClassificationResult{Label: "", Probability: -1}, err
+		}
+
+		result = c.normalizeDistribution(result)
+
+		if c.targetColumn != "" {
+			result.Label = c.targetColumn
+		} else {
+			result.Label = ""
+		}
+
+		return result, nil
 	}
+}
 
-	return result, nil
+// PredictMany classifies every text. Failures of individual inputs are reported per item in the BatchResult,
+// the returned error is only set for invalid arguments or a done context.
+func (c *TaoClassifier) PredictMany(texts []string) (BatchResult, error) {
+	return c.PredictManyContext(context.Background(), texts)
 }
 
-func (c *TaoClassifier) PredictMany(texts []string) ([]ClassificationResult, error) {
+func (c *TaoClassifier) PredictManyContext(ctx context.Context, texts []string) (BatchResult, error) {
 	if len(texts) == 0 {
-		return []ClassificationResult{}, fmt.Errorf("texts cannot be empty")
+		return BatchResult{}, fmt.Errorf("texts cannot be empty")
 	}
 
-	var results []ClassificationResult
+	return c.predictTexts(ctx, texts, false)
+}
 
-	for _, text := range texts {
-		// TODO: if multiple values fit into the prompt, then use a single prompt - this is an area of optimization
-		result, err := c.PredictOne(text)
+// predictTexts classifies texts in batched prompts when BatchSize > 1, otherwise one prompt per text.
+// Logprob scoring reads the first token of the response, multi-label predictions need a probability per
+// label and self-consistency samples every text several times, so they always use one prompt per text.
+// With raw the predictions are neither calibrated nor abstained from, see predictText.
+func (c *TaoClassifier) predictTexts(ctx context.Context, texts []string, raw bool) (BatchResult, error) {
+	if c.batchSize > 1 && c.scoringMode != ScoringLogprobs && !c.multiLabel && c.selfConsistencySamples <= 1 {
+		return c.predictBatched(ctx, texts, raw)
+	}
 
-		if err != nil {
-			return []ClassificationResult{}, err
-		}
+	return predictConcurrently(ctx, len(texts), c.concurrency, func(ctx context.Context, index int) (ClassificationResult, error) {
+		return c.predictText(ctx, texts[index], raw)
+	})
+}
 
-		results = append(results, result)
+// predictText is PredictOneContext, or with raw the prediction before calibration and abstention, which
+// Calibrate fits on without changing the settings that concurrent predictions read.
+func (c *TaoClassifier) predictText(ctx context.Context, text string, raw bool) (ClassificationResult, error) {
+	if raw {
+		return c.predictOne(ctx, text, false)
 	}
 
-	return results, nil
+	return c.PredictOneContext(ctx, text)
 }
 
-func (c *TaoClassifier) PredictOneObject(obj any) (ClassificationResult, error) {
+func (c *TaoClassifier) PredictOneObject(obj any, opts ...PredictOptions) (ClassificationResult, error) {
+	return c.PredictOneObjectContext(context.Background(), obj, opts...)
+}
+
+func (c *TaoClassifier) PredictOneObjectContext(ctx context.Context, obj any, opts ...PredictOptions) (ClassificationResult, error) {
 	objStr, err := json.Marshal(obj)
 	if err != nil {
 		return ClassificationResult{Label: "", Probability: -1}, fmt.Errorf("PredictOneObject: failed to marshal object: %v", err)
 	}
 
-	return c.PredictOne(string(objStr))
+	return c.PredictOneContext(ctx, string(objStr), opts...)
+}
+
+func (c *TaoClassifier) PredictManyObjects(objs []any) (BatchResult, error) {
+	return c.PredictManyObjectsContext(context.Background(), objs)
 }
 
-func (c *TaoClassifier) PredictManyObjects(objs []any) ([]ClassificationResult, error) {
+// PredictManyObjectsContext is like PredictManyObjects but stops starting predictions once ctx is done.
+// Inputs that didn't complete fail with ctx.Err(), which is also returned. An object that can't be marshaled
+// fails with ErrInvalidInput and the other objects are still predicted.
+func (c *TaoClassifier) PredictManyObjectsContext(ctx context.Context, objs []any) (BatchResult, error) {
+	return c.predictObjects(ctx, objs, false)
+}
 
+// predictObjects classifies the JSON of every object with predictTexts.
+func (c *TaoClassifier) predictObjects(ctx context.Context, objs []any, raw bool) (BatchResult, error) {
 	if len(objs) == 0 {
-		return []ClassificationResult{}, fmt.Errorf("PredictManyObjects: objs cannot be empty")
+		return BatchResult{}, fmt.Errorf("PredictManyObjects: objs cannot be empty")
 	}
 
-	var results []ClassificationResult
+	results := make([]ClassificationResult, len(objs))
+	errs := make([]error, len(objs))
+	texts := []string{}
+	indexes := []int{} // index in objs of every text
 
-	for _, obj := range objs {
-		result, err := c.PredictOneObject(obj)
+	for index, obj := range objs {
+		objStr, err := json.Marshal(obj)
 
 		if err != nil {
-			results = append(results, ClassificationResult{Label: "", PredictedClass: "", Probability: -1})
-		} else {
-			results = append(results, result)
+			errs[index] = fmt.Errorf("PredictManyObjects: failed to marshal object %d: %w: %v", index, ErrInvalidInput, err)
+			continue
 		}
+
+		texts = append(texts, string(objStr))
+		indexes = append(indexes, index)
+	}
+
+	if len(texts) == 0 {
+		return newBatchResult(results, errs), nil
+	}
+
+	batch, err := c.predictTexts(ctx, texts, raw)
+
+	for i, item := range batch.Items {
+		results[indexes[i]] = item.Result
+		errs[indexes[i]] = item.Err
 	}
 
-	return results, nil
+	return newBatchResult(results, errs), err
+}
+
+func (c *TaoClassifier) PredictOneRowItem(rowItem RowItem, opts ...PredictOptions) (ClassificationResult, error) {
+	return c.PredictOneRowItemContext(context.Background(), rowItem, opts...)
 }
 
-func (c *TaoClassifier) PredictOneRowItem(rowItem RowItem) (ClassificationResult, error) {
+func (c *TaoClassifier) PredictOneRowItemContext(ctx context.Context, rowItem RowItem, opts ...PredictOptions) (ClassificationResult, error) {
 	var rowItemAny any = rowItem
 
-	return c.PredictOneObject(rowItemAny)
+	return c.PredictOneObjectContext(ctx, rowItemAny, opts...)
+}
+
+func (c *TaoClassifier) PredictManyRowItems(rowItems []RowItem) (BatchResult, error) {
+	return c.PredictManyRowItemsContext(context.Background(), rowItems)
 }
 
-func (c *TaoClassifier) PredictManyRowItems(rowItems []RowItem) ([]ClassificationResult, error) {
+func (c *TaoClassifier) PredictManyRowItemsContext(ctx context.Context, rowItems []RowItem) (BatchResult, error) {
 	var rowItemsAny []any
 
 	for _, rowItem := range rowItems {
 		rowItemsAny = append(rowItemsAny, rowItem)
 	}
 
-	return c.PredictManyObjects(rowItemsAny)
+	return c.PredictManyObjectsContext(ctx, rowItemsAny)
 }
 
 func (c *TaoClassifier) GetSavableModel() SavedTaoModel {
-	return SavedTaoModel{
+	savedTaoModel := SavedTaoModel{
 		ModelId:          c.modelId,
 		Prompts:          c.prompts,
 		Temperature:      c.temperature,
 		PromptSampleSize: c.promptSampleSize,
 		TargetColumn:     c.targetColumn,
+		Calibration:      c.calibration,
+		DecisionRules:    c.decisionRules,
+		Examples:         c.examples,
+	}
+
+	if c.multiLabel {
+		savedTaoModel.MultiLabel = true
+		savedTaoModel.MultiLabelThreshold = c.multiLabelThreshold
+		savedTaoModel.LabelSeparator = c.labelSeparator
 	}
+
+	return savedTaoModel
 }
@@ -1,14 +1,79 @@
 package core
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 )
 
+var profileLabelPattern = regexp.MustCompile(`for the label (\S+) given`)
+var contextLabelPattern = regexp.MustCompile(`(?m)^(\S+): `)
+
+// newFakeClassifierProvider answers profile generation and classification prompts offline.
+// Profiles describe the requested label and predictions pick the first label listed in the context.
+func newFakeClassifierProvider() *FakeProvider {
+	return NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+		if strings.Contains(request.SystemPrompt(), "classification profile") {
+			matches := profileLabelPattern.FindStringSubmatch(request.LastUserMessage())
+
+			if len(matches) < 2 {
+				return "", fmt.Errorf("no label in prompt")
+			}
+
+			return fmt.Sprintf("```json\n{\"label\": \"%s\", \"description\": [\"Rows labelled %s share similar study habits.\"]}\n```", matches[1], matches[1]), nil
+		}
+
+		labels := []string{}
+
+		for _, match := range contextLabelPattern.FindAllStringSubmatch(request.SystemPrompt(), -1) {
+			labels = append(labels, match[1])
+		}
+
+		if len(labels) == 0 {
+			return "", fmt.Errorf("no labels in prompt")
+		}
+
+		sort.Strings(labels)
+
+		return fmt.Sprintf(`{"predicted_class": "%s", "probability": 0.8}`, labels[0]), nil
+	})
+}
+
+// newTestClassifier builds a classifier backed by newFakeClassifierProvider unless a provider is given.
+func newTestClassifier(t *testing.T, opts ...TaoClassifierOptions) *TaoClassifier {
+	t.Helper()
+
+	options := TaoClassifierOptions{}
+
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if options.Provider == nil {
+		options.Provider = newFakeClassifierProvider()
+	}
+
+	classifier, err := NewTaoClassifier(options)
+
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	return classifier
+}
+
 func TestPredictOne(t *testing.T) {
 
 	t.Run("Correctly classifies single example", func(t *testing.T) {
-		classifier := NewTaoClassifier()
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: newCassetteProvider(t, "predict_one_cat_dog")})
 
 		prompts := map[Label][]LabelDescription{
 			"cat": {"Cats are generally more independent and aloof than dogs, who are often more social and affectionate. Cats are also more territorial and may be more aggressive when defending their territory.  Cats are self-grooming animals, using their tongues to keep their coats clean and healthy. Cats use body language and vocalizations, such as meowing and purring, to communicate."},
@@ -27,6 +92,11 @@ func TestPredictOne(t *testing.T) {
 			t.Errorf("Expected probability > 0.5, got %v", result.Probability)
 		}
 
+		// the fixture answers like strict structured output: plain JSON with a distribution over both labels
+		if len(result.Distribution) != 2 || result.Distribution["cat"] != 0.95 {
+			t.Errorf("Expected the distribution of the response, got %v", result.Distribution)
+		}
+
 		if err != nil {
 			t.Errorf("Expected no error, got %v", err)
 		}
@@ -53,7 +123,7 @@ func TestPredictOne(t *testing.T) {
 			TargetColumn:        "ParentalSupport",
 		}
 
-		classifier := NewTaoClassifier(params)
+		classifier := newTestClassifier(t, params)
 
 		classifier.Train()
 
@@ -82,7 +152,7 @@ func TestGenerateClassificationProfile(t *testing.T) {
 
 	t.Run("Generates a classifier profile with correct schema", func(t *testing.T) {
 
-		classifier := NewTaoClassifier()
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: newCassetteProvider(t, "generate_classifier_profile")})
 
 		rowItem := RowItem{
 			"student_id":                 "1",
@@ -116,7 +186,7 @@ func TestPredictOneObject(t *testing.T) {
 			TargetColumn:        "ParentalSupport",
 		}
 
-		classifier := NewTaoClassifier(params)
+		classifier := newTestClassifier(t, params)
 
 		classifier.Train()
 
@@ -161,7 +231,7 @@ func TestPredictOneObjectNumericalClasses(t *testing.T) {
 			TargetColumn:        "ParentalSupport",
 		}
 
-		classifier := NewTaoClassifier(params)
+		classifier := newTestClassifier(t, params)
 
 		classifier.PromptTrain(map[Label][]LabelDescription{
 			"0": {"low parental support"},
@@ -205,7 +275,7 @@ func TestPredictOneObjectNumericalClasses(t *testing.T) {
 func TestArePromptsLoaded(t *testing.T) {
 
 	t.Run("Returns false when no prompts are loaded", func(t *testing.T) {
-		classifier := NewTaoClassifier()
+		classifier := newTestClassifier(t)
 
 		_, err := classifier.ArePromptsLoaded()
 
@@ -215,7 +285,7 @@ func TestArePromptsLoaded(t *testing.T) {
 	})
 
 	t.Run("Returns true when prompts are loaded", func(t *testing.T) {
-		classifier := NewTaoClassifier()
+		classifier := newTestClassifier(t)
 
 		classifier.PromptTrain(map[Label][]LabelDescription{
 			"0": {"low parental support"},
@@ -236,7 +306,7 @@ func TestArePromptsLoaded(t *testing.T) {
 
 	t.Run("Returns false when a particular class doesn't have descriptions", func(t *testing.T) {
 
-		classifier := NewTaoClassifier()
+		classifier := newTestClassifier(t)
 
 		classifier.PromptTrain(map[Label][]LabelDescription{
 			"0": {"low parental support"},
@@ -265,7 +335,7 @@ func TestSaveModel(t *testing.T) {
 			PromptSampleSize:    2,
 		}
 
-		classifier := NewTaoClassifier(params)
+		classifier := newTestClassifier(t, params)
 
 		classifier.Train()
 
@@ -288,7 +358,7 @@ func TestLoadModel(t *testing.T) {
 			PromptSampleSize: 2,
 		}
 
-		classifier := NewTaoClassifier(params)
+		classifier := newTestClassifier(t, params)
 
 		classifier.PromptTrain(map[Label][]LabelDescription{
 			"apple":  {"apples are red"},
@@ -305,7 +375,7 @@ func TestLoadModel(t *testing.T) {
 			t.Errorf("Expected non-empty status, got empty")
 		}
 
-		loadedClassifier := NewTaoClassifier(TaoClassifierOptions{
+		loadedClassifier := newTestClassifier(t, TaoClassifierOptions{
 			ModelId: "test_model",
 		})
 
@@ -328,3 +398,65 @@ func TestLoadModel(t *testing.T) {
 
 	})
 }
+
+func TestContextCancellation(t *testing.T) {
+	t.Run("TrainContext stops when the context is cancelled", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{
+			TrainingDatasetPath: "../datasets/student_performance.csv",
+			TargetColumn:        "ParentalSupport",
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := classifier.TrainContext(ctx)
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("PredictManyObjectsContext returns the context error instead of placeholders", func(t *testing.T) {
+		classifier := newTestClassifier(t)
+
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"positive": {"positive sentiment"},
+			"negative": {"negative sentiment"},
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := classifier.PredictManyObjectsContext(ctx, []any{"great", "awful"})
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("PredictOneContext deadline reaches the HTTP call", func(t *testing.T) {
+		release := make(chan struct{})
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		defer server.Close()
+		defer close(release)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: NewOllamaProvider(OllamaProviderOptions{BaseURL: server.URL})})
+
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"cat": {"meows"},
+			"dog": {"barks"},
+		})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		_, err := classifier.PredictOneContext(ctx, "Meow")
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+const (
+	decisionRuleRows    = 3 // rows of each label shown in a decision rule prompt
+	maxRulesPerPair     = 3
+	decisionRuleHeading = "Decision rules for similar classes"
+)
+
+// DecisionRule tells two labels apart, e.g. "1 vs 2: 1 rather than 2 when the battery is below 1000 mAh".
+type DecisionRule struct {
+	Labels []Label  `json:"labels"` // the two labels, sorted
+	Rules  []string `json:"rules"`
+}
+
+type decisionRuleResponse struct {
+	Rules []string `json:"rules"`
+}
+
+func (decisionRuleResponse) requiredJsonKeys() []string {
+	return []string{"rules"}
+}
+
+// generateDecisionRules asks the model for the features that tell every pair of classes apart, showing rows
+// of both classes at once. It makes at most budget calls when budget >= 0 and returns the number of calls.
+// With MaxTrainingRows only rows that were already used for the profiles are shown.
+func (c *TaoClassifier) generateDecisionRules(ctx context.Context, groups map[Label][]int, classes []Label, selectedRows map[int]bool, budget int) (int, error) {
+	candidates := map[Label][]int{}
+
+	for _, class := range classes {
+		for _, row := range groups[class] {
+			if c.maxTrainingRows == 0 || selectedRows[row] {
+				candidates[class] = append(candidates[class], row)
+			}
+		}
+	}
+
+	calls := 0
+
+	for i, a := range classes {
+		for _, b := range classes[i+1:] {
+			if len(candidates[a]) == 0 || len(candidates[b]) == 0 {
+				continue
+			}
+
+			if budget >= 0 && calls >= budget {
+				if c.verbose {
+					fmt.Println("Train: reached MaxTrainingCalls, skipping the remaining decision rules")
+				}
+
+				return calls, nil
+			}
+
+			if err := ctx.Err(); err != nil {
+				return calls, err
+			}
+
+			rules, err := c.generateDecisionRule(ctx, a, c.sampleRows(candidates[a]), b, c.sampleRows(candidates[b]))
+			calls++
+
+			if err != nil {
+				return calls, fmt.Errorf("Train: failed to generate decision rules for %s vs %s: %w", a, b, err)
+			}
+
+			c.setDecisionRule(DecisionRule{Labels: []Label{a, b}, Rules: rules})
+		}
+	}
+
+	return calls, nil
+}
+
+// sampleRows picks up to decisionRuleRows random rows.
+func (c *TaoClassifier) sampleRows(rows []int) []RowItem {
+	sampled := []RowItem{}
+
+	for _, index := range rand.Perm(len(rows))[:min(len(rows), decisionRuleRows)] {
+		sampled = append(sampled, c.dataset[rows[index]])
+	}
+
+	return sampled
+}
+
+func (c *TaoClassifier) generateDecisionRule(ctx context.Context, a Label, rowsA []RowItem, b Label, rowsB []RowItem) ([]string, error) {
+	systemPrompt := fmt.Sprintf(`You are an AI assistant that performs classification.
+	You are tasked with finding the features that tell two similar labels apart, given row items of both labels.
+	Respond in JSON with { "rules": string[] }, with at most %d short rules such as "<label> rather than <other label> when ...".
+	Only include differences that hold for the rows of both labels, not features they share.
+	Target Column for Classification: %s`, maxRulesPerPair, c.targetColumn)
+
+	userPrompt := fmt.Sprintf("Write decision rules for %s vs %s.\nRow items of the label %s:\n%s\nRow items of the label %s:\n%s", a, b, a, formatRowItems(rowsA), b, formatRowItems(rowsB))
+
+	text, err := c.ai.GenerateTextContext(ctx, userPrompt, GenerateTextOptions{Verbose: false, System: systemPrompt, ResponseSchema: decisionRuleSchema()})
+
+	if c.verbose {
+		fmt.Println("System Prompt: ", systemPrompt)
+		fmt.Println("Prompt: ", userPrompt)
+		fmt.Println("Generated Text: ", text)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := CleanGPTJson[decisionRuleResponse](text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return consolidateDescriptions(response.Rules, maxRulesPerPair), nil
+}
+
+// setDecisionRule adds rule, replacing an existing rule for the same labels, and keeps the rules sorted.
+func (c *TaoClassifier) setDecisionRule(rule DecisionRule) {
+	rule.Labels = append([]Label{}, rule.Labels...)
+	sort.Strings(rule.Labels)
+
+	rules := []DecisionRule{}
+
+	for _, existing := range c.decisionRules {
+		if strings.Join(existing.Labels, "\x00") != strings.Join(rule.Labels, "\x00") {
+			rules = append(rules, existing)
+		}
+	}
+
+	if len(rule.Rules) > 0 {
+		rules = append(rules, rule)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return strings.Join(rules[i].Labels, "\x00") < strings.Join(rules[j].Labels, "\x00")
+	})
+
+	c.decisionRules = rules
+}
+
+// removeDecisionRules drops the rules that tell label apart from another label.
+func (c *TaoClassifier) removeDecisionRules(label Label) {
+	rules := []DecisionRule{}
+
+	for _, rule := range c.decisionRules {
+		if !Contains(rule.Labels, label) {
+			rules = append(rules, rule)
+		}
+	}
+
+	c.decisionRules = rules
+}
+
+// formatDecisionRules lists the decision rules for prediction prompts, "" when there are none.
+func (c *TaoClassifier) formatDecisionRules() string {
+	if len(c.decisionRules) == 0 {
+		return ""
+	}
+
+	formatted := decisionRuleHeading + "\n"
+
+	for _, rule := range c.decisionRules {
+		for _, text := range rule.Rules {
+			formatted += fmt.Sprintf("%s: %s\n", strings.Join(rule.Labels, " vs "), text)
+		}
+	}
+
+	return formatted
+}
+
+func decisionRuleSchema() *ResponseSchema {
+	return &ResponseSchema{
+		Name: "decision_rules",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"rules": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"type": "string"},
+				},
+			},
+			"required":             []string{"rules"},
+			"additionalProperties": false,
+		},
+		Strict: true,
+	}
+}
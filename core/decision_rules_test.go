@@ -0,0 +1,125 @@
+package core
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecisionRules(t *testing.T) {
+	dataset := []RowItem{
+		{"ram": "512", "price_range": "1"},
+		{"ram": "600", "price_range": "1"},
+		{"ram": "1500", "price_range": "2"},
+		{"ram": "3800", "price_range": "3"},
+	}
+
+	newProvider := func() *FakeProvider {
+		return NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			if matches := profileLabelPattern.FindStringSubmatch(request.LastUserMessage()); len(matches) == 2 {
+				return fmt.Sprintf(`{"label": "%s", "description": ["phones in range %s"]}`, matches[1], matches[1]), nil
+			}
+
+			pair := strings.TrimSuffix(strings.SplitN(strings.TrimPrefix(request.LastUserMessage(), "Write decision rules for "), "\n", 2)[0], ".")
+
+			return fmt.Sprintf(`{"rules": ["%s: compare the ram", "%s: Compare the RAM"]}`, pair, pair), nil
+		})
+	}
+
+	t.Run("Generates rules for every pair of classes from rows of both. ", func(t *testing.T) {
+		provider := newProvider()
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, TargetColumn: "price_range", PromptSampleSize: 1, PairwiseRules: true})
+		classifier.dataset = dataset
+
+		if err := classifier.Train(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		rules := classifier.GetSavableModel().DecisionRules
+		expected := []DecisionRule{
+			{Labels: []Label{"1", "2"}, Rules: []string{"1 vs 2: compare the ram"}},
+			{Labels: []Label{"1", "3"}, Rules: []string{"1 vs 3: compare the ram"}},
+			{Labels: []Label{"2", "3"}, Rules: []string{"2 vs 3: compare the ram"}},
+		}
+
+		if !reflect.DeepEqual(rules, expected) {
+			t.Errorf("Expected a deduplicated rule per pair, got %+v", rules)
+		}
+
+		requests := provider.Requests()
+		prompt := requests[len(requests)-1].LastUserMessage()
+
+		if !strings.Contains(prompt, "price_range: 2") || !strings.Contains(prompt, "price_range: 3") || strings.Contains(prompt, "price_range: 1") {
+			t.Errorf("Expected rows of both classes in the prompt, got %v", prompt)
+		}
+	})
+
+	t.Run("Respects the call budget. ", func(t *testing.T) {
+		provider := newProvider()
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, TargetColumn: "price_range", PromptSampleSize: 1, PairwiseRules: true, MaxTrainingCalls: 4})
+		classifier.dataset = dataset
+
+		if err := classifier.Train(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(provider.Requests()) != 4 || len(classifier.GetSavableModel().DecisionRules) != 1 {
+			t.Errorf("Expected 3 profiles and 1 decision rule, got %v requests and %+v", len(provider.Requests()), classifier.GetSavableModel().DecisionRules)
+		}
+	})
+
+	t.Run("Uses saved rules in the prediction prompt. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{ModelId: "test_decision_rules_model", Provider: newProvider(), TargetColumn: "price_range", PromptSampleSize: 1, PairwiseRules: true})
+		classifier.dataset = dataset
+
+		if err := classifier.Train(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, err := classifier.SaveModel(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		provider := NewFakeProvider(`{"predicted_class": "1", "probability": 0.8}`)
+		loadedClassifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider})
+
+		if _, err := loadedClassifier.LoadModel("test_decision_rules_model"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, err := loadedClassifier.PredictOne("ram: 550"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		systemPrompt := provider.Requests()[0].SystemPrompt()
+
+		if !strings.Contains(systemPrompt, "Decision rules for similar classes\n1 vs 2: 1 vs 2: compare the ram") {
+			t.Errorf("Expected the decision rules in the prompt, got %v", systemPrompt)
+		}
+	})
+
+	t.Run("Drops the rules of removed labels. ", func(t *testing.T) {
+		classifier := newTestClassifier(t)
+		classifier.PromptTrain(map[Label][]LabelDescription{"1": {"cheap"}, "2": {"mid"}, "3": {"expensive"}})
+		classifier.setDecisionRule(DecisionRule{Labels: []Label{"1", "2"}, Rules: []string{"1 rather than 2 when ram is low"}})
+		classifier.setDecisionRule(DecisionRule{Labels: []Label{"2", "3"}, Rules: []string{"3 rather than 2 when ram is high"}})
+
+		if _, err := classifier.RemovePrompt("1"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if rules := classifier.GetSavableModel().DecisionRules; len(rules) != 1 || !reflect.DeepEqual(rules[0].Labels, []Label{"2", "3"}) {
+			t.Errorf("Expected only the 2 vs 3 rule, got %v", rules)
+		}
+
+		classifier.ClearPrompts()
+		classifier.PromptTrain(map[Label][]LabelDescription{"x": {"new"}, "y": {"labels"}})
+
+		if rules := classifier.formatDecisionRules(); rules != "" || len(classifier.GetSavableModel().DecisionRules) != 0 {
+			t.Errorf("Expected no rules after ClearPrompts, got %v", rules)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+// LabelScore is one label with its normalized score.
+type LabelScore struct {
+	Label Label
+	Score float64
+}
+
+// TopK returns the k labels with the highest scores in Distribution, highest first. k <= 0 returns all labels.
+// Multi-label results have no Distribution, their labels are ranked by LabelProbabilities instead.
+func (r ClassificationResult) TopK(k int) []LabelScore {
+	scores := []LabelScore{}
+	distribution := r.Distribution
+
+	if len(distribution) == 0 {
+		distribution = r.LabelProbabilities
+	}
+
+	for label, score := range distribution {
+		scores = append(scores, LabelScore{Label: label, Score: score})
+	}
+
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Score != scores[j].Score {
+			return scores[i].Score > scores[j].Score
+		}
+
+		return scores[i].Label < scores[j].Label
+	})
+
+	if k > 0 && k < len(scores) {
+		scores = scores[:k]
+	}
+
+	return scores
+}
+
+// TopK classifies text and returns the k most likely labels with scores summing to 1 over all labels. In
+// multi-label mode the scores are the independent label probabilities, which don't sum to 1.
+func (c *TaoClassifier) TopK(text string, k int) ([]LabelScore, error) {
+	return c.TopKContext(context.Background(), text, k)
+}
+
+func (c *TaoClassifier) TopKContext(ctx context.Context, text string, k int) ([]LabelScore, error) {
+	result, err := c.PredictOneContext(ctx, text)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result.TopK(k), nil
+}
+
+// normalizeDistribution maps the scores reported by the model onto the known labels and the predicted
+// class, and scales them to sum to 1. Without usable scores the reported probability goes to the
+// predicted class and the rest is split evenly. Probability is set to the score of the predicted class.
+func (c *TaoClassifier) normalizeDistribution(result ClassificationResult) ClassificationResult {
+	labels, _ := c.GetAvailableLabels()
+	predicted, _ := result.PredictedClass.(string)
+
+	distribution := map[Label]float64{}
+
+	for _, label := range labels {
+		distribution[label] = 0
+	}
+
+	if predicted != "" {
+		distribution[predicted] = 0
+	}
+
+	if len(distribution) == 0 {
+		return result
+	}
+
+	total := 0.0
+
+	for key, score := range result.Distribution {
+		label, ok := MatchLabel(key, labels)
+
+		if !ok || score <= 0 || math.IsNaN(score) || math.IsInf(score, 0) {
+			continue
+		}
+
+		distribution[label] += score
+		total += score
+	}
+
+	if total > 0 {
+		for label := range distribution {
+			distribution[label] /= total
+		}
+	} else {
+		probability := math.Max(0, math.Min(1, result.Probability))
+
+		if len(distribution) == 1 {
+			probability = 1
+		}
+
+		for label := range distribution {
+			if label == predicted {
+				distribution[label] = probability
+			} else {
+				distribution[label] = (1 - probability) / float64(len(distribution)-1)
+			}
+		}
+	}
+
+	result.Distribution = distribution
+
+	if predicted != "" {
+		result.Probability = distribution[predicted]
+	}
+
+	return result
+}
@@ -0,0 +1,79 @@
+package core
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistribution(t *testing.T) {
+	prompts := map[Label][]LabelDescription{
+		"cat":  {"meows"},
+		"dog":  {"barks"},
+		"bird": {"sings"},
+	}
+
+	t.Run("Normalizes the scores reported by the model. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"predicted_class": "cat", "probability": 0.9, "distribution": {"Cat": 0.6, "dog": 0.3, "bird": 0.3}}`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Meow")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if math.Abs(result.Distribution["cat"]-0.5) > 1e-9 || math.Abs(result.Distribution["dog"]-0.25) > 1e-9 {
+			t.Errorf("Expected scores normalized to sum to 1, got %v", result.Distribution)
+		}
+
+		if math.Abs(result.Probability-0.5) > 1e-9 {
+			t.Errorf("Expected the probability of the predicted class, got %v", result.Probability)
+		}
+	})
+
+	t.Run("Spreads the remaining probability when the model reports no scores. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"predicted_class": "dog", "probability": 0.8}`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Woof")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if math.Abs(result.Distribution["dog"]-0.8) > 1e-9 || math.Abs(result.Distribution["cat"]-0.1) > 1e-9 || math.Abs(result.Distribution["bird"]-0.1) > 1e-9 {
+			t.Errorf("Expected 0.8 for dog and 0.1 for the others, got %v", result.Distribution)
+		}
+	})
+
+	t.Run("TopK returns the most likely labels first. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"predicted_class": "bird", "probability": 0.5, "distribution": {"cat": 0.2, "dog": 0.3, "bird": 0.5}}`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider})
+		classifier.PromptTrain(prompts)
+
+		scores, err := classifier.TopK("Tweet", 2)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(scores) != 2 || scores[0].Label != "bird" || scores[1].Label != "dog" || math.Abs(scores[1].Score-0.3) > 1e-9 {
+			t.Errorf("Expected bird then dog, got %v", scores)
+		}
+	})
+
+	t.Run("TopK breaks ties by label and returns all labels for k <= 0. ", func(t *testing.T) {
+		result := ClassificationResult{Distribution: map[Label]float64{"b": 0.25, "a": 0.25, "c": 0.5}}
+
+		scores := result.TopK(0)
+
+		if len(scores) != 3 || scores[0].Label != "c" || scores[1].Label != "a" || scores[2].Label != "b" {
+			t.Errorf("Expected c, a, b, got %v", scores)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrMissingAPIKey = errors.New("API key is not set")
+	ErrEmptyResponse = errors.New("provider returned no content")
+	ErrEmptyInput    = errors.New("text cannot be empty")
+	ErrInvalidInput  = errors.New("input cannot be converted to text")
+	ErrUnknownLabel  = errors.New("predicted class is not one of the known labels")
+	ErrNoLogprobs    = errors.New("provider returned no logprobs")
+	ErrTruncated     = errors.New("model output was cut off at the token limit")
+)
+
+// ProviderError is returned when a call to the LLM provider fails.
+type ProviderError struct {
+	Provider   string
+	StatusCode int // 0 when no response was received, e.g. network errors or cancellation
+	Message    string
+	RetryAfter time.Duration // delay requested by the provider through Retry-After, if any
+	Err        error
+}
+
+func (e *ProviderError) Error() string {
+	message := e.Message
+
+	if message == "" && e.Err != nil {
+		message = e.Err.Error()
+	}
+
+	if e.StatusCode != 0 && message == "" {
+		return fmt.Sprintf("%s: request failed with status %d", e.Provider, e.StatusCode)
+	}
+
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s: request failed with status %d: %s", e.Provider, e.StatusCode, message)
+	}
+
+	return fmt.Sprintf("%s: request failed: %s", e.Provider, message)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is returned when the model output can't be parsed into the expected structure.
+type ParseError struct {
+	Raw string // the raw model output
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("failed to parse model output: %v", e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigError is returned for invalid configuration such as missing API keys or unreadable datasets.
+type ConfigError struct {
+	Field string
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid configuration for %s: %v", e.Field, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError is returned when the model output was parsed but isn't acceptable, e.g. an unknown label.
+type ValidationError struct {
+	Value interface{} // the rejected value
+	Raw   string      // the raw model output the value was parsed from
+	Err   error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid model output %v: %v", e.Value, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
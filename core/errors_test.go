@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTypedErrors(t *testing.T) {
+	t.Run("Missing API key is a ConfigError wrapping ErrMissingAPIKey. ", func(t *testing.T) {
+		t.Setenv("OPENAI_API_KEY", "")
+
+		_, err := NewAI()
+
+		var configErr *ConfigError
+
+		if !errors.As(err, &configErr) {
+			t.Errorf("Expected a ConfigError, got %v", err)
+		}
+
+		if !errors.Is(err, ErrMissingAPIKey) {
+			t.Errorf("Expected ErrMissingAPIKey, got %v", err)
+		}
+	})
+
+	t.Run("NewTaoClassifier returns a ConfigError for an unreadable dataset. ", func(t *testing.T) {
+		_, err := NewTaoClassifier(TaoClassifierOptions{
+			TrainingDatasetPath: "../datasets/missing.csv",
+			TargetColumn:        "ParentalSupport",
+			Provider:            NewFakeProvider(),
+		})
+
+		var configErr *ConfigError
+
+		if !errors.As(err, &configErr) || configErr.Field != "TrainingDatasetPath" {
+			t.Errorf("Expected a ConfigError for TrainingDatasetPath, got %v", err)
+		}
+	})
+
+	t.Run("NewTaoClassifier returns a ConfigError when TargetColumn is missing. ", func(t *testing.T) {
+		_, err := NewTaoClassifier(TaoClassifierOptions{
+			TrainingDatasetPath: "../datasets/student_performance.csv",
+			Provider:            NewFakeProvider(),
+		})
+
+		var configErr *ConfigError
+
+		if !errors.As(err, &configErr) || configErr.Field != "TargetColumn" {
+			t.Errorf("Expected a ConfigError for TargetColumn, got %v", err)
+		}
+	})
+
+	t.Run("Provider failures are ProviderErrors with the status code. ", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		ai := NewAIWithProvider(NewOllamaProvider(OllamaProviderOptions{BaseURL: server.URL}), AIOptions{RetryPolicy: &RetryPolicy{MaxAttempts: 1}})
+
+		_, err := ai.GenerateText("Hello")
+
+		var providerErr *ProviderError
+
+		if !errors.As(err, &providerErr) {
+			t.Fatalf("Expected a ProviderError, got %v", err)
+		}
+
+		if providerErr.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Expected status 503, got %v", providerErr.StatusCode)
+		}
+	})
+
+	t.Run("Cancelled requests unwrap to the context error. ", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := NewOllamaProvider(OllamaProviderOptions{BaseURL: "http://127.0.0.1:1"}).Chat(ctx, ChatRequest{})
+
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected context.Canceled, got %v", err)
+		}
+	})
+
+	t.Run("Unparseable model output is a ParseError with the raw output. ", func(t *testing.T) {
+		ai := NewAIWithProvider(NewFakeProvider("I can't answer that."))
+
+		_, err := ai.GenerateObject("Generate an object.", `{"name": "string"}`)
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) {
+			t.Fatalf("Expected a ParseError, got %v", err)
+		}
+
+		if parseErr.Raw != "I can't answer that." {
+			t.Errorf("Expected the raw output, got %v", parseErr.Raw)
+		}
+	})
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FakeProvider is a deterministic Provider for tests. It either replays scripted responses in order
+// or delegates to a responder function, and records every request it receives.
+type FakeProvider struct {
+	mu        sync.Mutex
+	responses []FakeResponse
+	respond   func(request ChatRequest) (string, error)
+	requests  []ChatRequest
+}
+
+type FakeResponse struct {
+	Content    string
+	Logprobs   []TokenLogprob
+	StopReason string // "stop" when empty
+	Err        error
+}
+
+// NewFakeProvider returns a provider that answers with the given contents in order
+// and fails once they are used up.
+func NewFakeProvider(contents ...string) *FakeProvider {
+	responses := []FakeResponse{}
+
+	for _, content := range contents {
+		responses = append(responses, FakeResponse{Content: content})
+	}
+
+	return NewScriptedFakeProvider(responses...)
+}
+
+// NewScriptedFakeProvider is like NewFakeProvider but lets the script include errors.
+func NewScriptedFakeProvider(responses ...FakeResponse) *FakeProvider {
+	return &FakeProvider{
+		responses: responses,
+	}
+}
+
+// NewFakeProviderFunc returns a provider that computes each response from the request.
+func NewFakeProviderFunc(respond func(request ChatRequest) (string, error)) *FakeProvider {
+	return &FakeProvider{
+		respond: respond,
+	}
+}
+
+func (p *FakeProvider) Chat(ctx context.Context, request ChatRequest) (ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return ChatResponse{}, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	index := len(p.requests)
+	p.requests = append(p.requests, request)
+
+	if p.respond != nil {
+		content, err := p.respond(request)
+
+		if err != nil {
+			return ChatResponse{}, err
+		}
+
+		return ChatResponse{Content: content, StopReason: "stop"}, nil
+	}
+
+	if index >= len(p.responses) {
+		return ChatResponse{}, fmt.Errorf("FakeProvider: no scripted response left for request %d", index+1)
+	}
+
+	response := p.responses[index]
+
+	if response.Err != nil {
+		return ChatResponse{}, response.Err
+	}
+
+	stopReason := response.StopReason
+
+	if stopReason == "" {
+		stopReason = "stop"
+	}
+
+	return ChatResponse{Content: response.Content, StopReason: stopReason, Logprobs: response.Logprobs}, nil
+}
+
+// Requests returns a copy of the requests received so far.
+func (p *FakeProvider) Requests() []ChatRequest {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]ChatRequest{}, p.requests...)
+}
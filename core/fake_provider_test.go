@@ -0,0 +1,60 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestFakeProvider(t *testing.T) {
+	t.Run("Replays scripted responses in order and fails when they run out. ", func(t *testing.T) {
+		provider := NewFakeProvider("first", "second")
+
+		for _, expected := range []string{"first", "second"} {
+			response, err := provider.Chat(context.Background(), ChatRequest{})
+
+			if err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+
+			if response.Content != expected {
+				t.Errorf("Expected %v, got %v", expected, response.Content)
+			}
+		}
+
+		_, err := provider.Chat(context.Background(), ChatRequest{})
+
+		if err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+
+		if len(provider.Requests()) != 3 {
+			t.Errorf("Expected 3 recorded requests, got %v", len(provider.Requests()))
+		}
+	})
+
+	t.Run("Returns scripted errors. ", func(t *testing.T) {
+		scriptedErr := errors.New("rate limited")
+		provider := NewScriptedFakeProvider(FakeResponse{Err: scriptedErr})
+
+		_, err := provider.Chat(context.Background(), ChatRequest{})
+
+		if !errors.Is(err, scriptedErr) {
+			t.Errorf("Expected scripted error, got %v", err)
+		}
+	})
+
+	t.Run("Computes responses from the request. ", func(t *testing.T) {
+		provider := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			return "echo: " + request.LastUserMessage(), nil
+		})
+
+		response, _ := provider.Chat(context.Background(), ChatRequest{
+			Messages: []Message{{Role: RoleSystem, Content: "system"}, {Role: RoleUser, Content: "Meow"}},
+		})
+
+		if response.Content != "echo: Meow" {
+			t.Errorf("Expected echo: Meow, got %v", response.Content)
+		}
+	})
+}
@@ -0,0 +1,361 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// FewShotStrategy decides which stored examples are shown in a prediction prompt.
+type FewShotStrategy string
+
+const (
+	FewShotRandom         FewShotStrategy = "random"         // random examples, the classes take turns
+	FewShotNearest        FewShotStrategy = "nearest"        // the examples whose text is most similar to the input
+	FewShotRepresentative FewShotStrategy = "representative" // the examples most similar to the rest of their class, the classes take turns
+)
+
+const (
+	defaultExamplesPerClass = 20
+	fewShotHeading          = "Labeled examples"
+)
+
+// Example is a labeled input shown to the model in prediction prompts.
+type Example struct {
+	Text  string `json:"text"`
+	Label Label  `json:"label"`
+}
+
+// AddExample stores a labeled example for few-shot prompts, it's only shown when FewShotK > 0.
+func (c *TaoClassifier) AddExample(label Label, text string) (bool, error) {
+	if label == "" {
+		return false, fmt.Errorf("label cannot be empty")
+	}
+
+	if text == "" {
+		return false, fmt.Errorf("text cannot be empty")
+	}
+
+	if c.hasExample(label, text) {
+		return true, nil
+	}
+
+	c.examples = append(c.examples, Example{Text: text, Label: label})
+	c.updateRepresentativeOrder(label)
+
+	return true, nil
+}
+
+// GetExamples returns a copy of the stored few-shot examples.
+func (c *TaoClassifier) GetExamples() []Example {
+	return append([]Example{}, c.examples...)
+}
+
+// RemoveExample removes a stored example.
+func (c *TaoClassifier) RemoveExample(label Label, text string) (bool, error) {
+	for index, example := range c.examples {
+		if example.Label == label && example.Text == text {
+			c.examples = append(c.examples[:index:index], c.examples[index+1:]...)
+			c.updateRepresentativeOrder()
+
+			return true, nil
+		}
+	}
+
+	return false, fmt.Errorf("example not found")
+}
+
+// ClearExamples removes every stored example.
+func (c *TaoClassifier) ClearExamples() {
+	c.examples = nil
+	c.representativeOrder = nil
+}
+
+// removeExamples drops the stored examples of label.
+func (c *TaoClassifier) removeExamples(label Label) {
+	examples := []Example{}
+
+	for _, example := range c.examples {
+		if example.Label != label {
+			examples = append(examples, example)
+		}
+	}
+
+	c.examples = examples
+	c.updateRepresentativeOrder()
+}
+
+// addDatasetExamples stores up to c.examplesPerClass random rows of every class of the dataset as examples.
+// The text of an example is the row without the target column, formatted like PredictOneRowItem does.
+func (c *TaoClassifier) addDatasetExamples() error {
+	counts := map[Label]int{}
+
+	for _, example := range c.examples {
+		counts[example.Label]++
+	}
+
+	for _, index := range rand.Perm(len(c.dataset)) {
+		row := c.dataset[index]
+		label := strings.TrimSpace(row[c.targetColumn])
+
+		if label == "" || counts[label] >= c.examplesPerClass {
+			continue
+		}
+
+		input := RowItem{}
+
+		for column, value := range row {
+			if column != c.targetColumn {
+				input[column] = value
+			}
+		}
+
+		text, err := json.Marshal(input)
+
+		if err != nil {
+			return fmt.Errorf("Train: failed to marshal example: %w", err)
+		}
+
+		if !c.hasExample(label, string(text)) {
+			c.examples = append(c.examples, Example{Text: string(text), Label: label})
+			counts[label]++
+		}
+	}
+
+	c.updateRepresentativeOrder()
+
+	return nil
+}
+
+// hasExample reports whether the example is already stored.
+func (c *TaoClassifier) hasExample(label Label, text string) bool {
+	for _, example := range c.examples {
+		if example.Label == label && example.Text == text {
+			return true
+		}
+	}
+
+	return false
+}
+
+// updateRepresentativeOrder orders the examples of classes, or of every class without classes, with
+// mostRepresentative. It runs whenever the examples change, so predictions don't compare the examples.
+func (c *TaoClassifier) updateRepresentativeOrder(classes ...Label) {
+	if c.fewShotStrategy == FewShotRandom {
+		return
+	}
+
+	if len(classes) == 0 || c.representativeOrder == nil {
+		c.representativeOrder = map[Label][]int{}
+		classes = nil
+	}
+
+	updated := map[Label]bool{}
+
+	for _, class := range classes {
+		updated[class] = true
+	}
+
+	indexes := map[Label][]int{}
+
+	for index, example := range c.examples {
+		if len(classes) == 0 || updated[example.Label] {
+			indexes[example.Label] = append(indexes[example.Label], index)
+		}
+	}
+
+	for class, classIndexes := range indexes {
+		c.representativeOrder[class] = mostRepresentative(c.examples, classIndexes)
+	}
+}
+
+// selectExamples picks c.fewShotK examples for text per c.fewShotStrategy, never text itself. Without a
+// text, e.g. for batched prompts, FewShotNearest falls back to FewShotRepresentative.
+func (c *TaoClassifier) selectExamples(text string) []Example {
+	candidates := []Example{}
+
+	for _, example := range c.examples {
+		if example.Text != text {
+			candidates = append(candidates, example)
+		}
+	}
+
+	if c.fewShotK <= 0 || len(candidates) == 0 {
+		return nil
+	}
+
+	strategy := c.fewShotStrategy
+
+	if strategy == FewShotNearest && text == "" {
+		strategy = FewShotRepresentative
+	}
+
+	if strategy == FewShotNearest {
+		input := termFrequencies(text)
+		scores := make([]float64, len(candidates))
+
+		for i, example := range candidates {
+			scores[i] = cosineSimilarity(input, termFrequencies(example.Text))
+		}
+
+		order := make([]int, len(candidates))
+
+		for i := range order {
+			order[i] = i
+		}
+
+		sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+
+		selected := []Example{}
+
+		for _, index := range order[:min(len(order), c.fewShotK)] {
+			selected = append(selected, candidates[index])
+		}
+
+		return selected
+	}
+
+	// indexes into c.examples per class, in the order they are shown
+	orders := map[Label][]int{}
+
+	if strategy == FewShotRepresentative {
+		for class, order := range c.representativeOrder {
+			orders[class] = order
+		}
+	} else {
+		for index, example := range c.examples {
+			orders[example.Label] = append(orders[example.Label], index)
+		}
+
+		for _, order := range orders {
+			rand.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+		}
+	}
+
+	classes := []Label{}
+
+	for class, order := range orders {
+		shown := []int{}
+
+		for _, index := range order {
+			if c.examples[index].Text != text {
+				shown = append(shown, index)
+			}
+		}
+
+		orders[class] = shown
+		classes = append(classes, class)
+	}
+
+	sort.Strings(classes)
+
+	// the classes take turns so every class is shown when K allows
+	selected := []Example{}
+
+	for _, sample := range interleave(orders, classes) {
+		if len(selected) == c.fewShotK {
+			break
+		}
+
+		selected = append(selected, c.examples[sample.row])
+	}
+
+	return selected
+}
+
+// mostRepresentative orders the examples at indexes by their mean text similarity to the other ones, highest
+// first.
+func mostRepresentative(examples []Example, indexes []int) []int {
+	frequencies := make([]map[string]float64, len(indexes))
+
+	for i, index := range indexes {
+		frequencies[i] = termFrequencies(examples[index].Text)
+	}
+
+	scores := make([]float64, len(indexes))
+
+	for i := range indexes {
+		for j := range indexes {
+			if i != j {
+				scores[i] += cosineSimilarity(frequencies[i], frequencies[j])
+			}
+		}
+	}
+
+	order := make([]int, len(indexes))
+
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool { return scores[order[i]] > scores[order[j]] })
+
+	ordered := []int{}
+
+	for _, i := range order {
+		ordered = append(ordered, indexes[i])
+	}
+
+	return ordered
+}
+
+// termFrequencies counts the lowercased words and numbers of text.
+func termFrequencies(text string) map[string]float64 {
+	frequencies := map[string]float64{}
+
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+
+	for _, word := range words {
+		frequencies[word]++
+	}
+
+	return frequencies
+}
+
+func cosineSimilarity(a map[string]float64, b map[string]float64) float64 {
+	dot, normA, normB := 0.0, 0.0, 0.0
+
+	for term, count := range a {
+		dot += count * b[term]
+		normA += count * count
+	}
+
+	for _, count := range b {
+		normB += count * count
+	}
+
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+
+	return dot / math.Sqrt(normA*normB)
+}
+
+// formatExamples lists the examples selected for text for prediction prompts, "" when there are none.
+func (c *TaoClassifier) formatExamples(text string) string {
+	examples := c.selectExamples(text)
+
+	if len(examples) == 0 {
+		return ""
+	}
+
+	formatted := "\n" + fewShotHeading + "\n"
+
+	for _, example := range examples {
+		formatted += fmt.Sprintf("Text: %s\nClass: %s\n", example.Text, example.Label)
+	}
+
+	return formatted
+}
+
+// predictionContext is the context of prediction prompts: the class descriptions, decision rules and the
+// few-shot examples for text.
+func (c *TaoClassifier) predictionContext(text string) string {
+	return c.formatClassDescriptors() + c.formatExamples(text)
+}
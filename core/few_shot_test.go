@@ -0,0 +1,186 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFewShotExamples(t *testing.T) {
+	addExamples := func(classifier *TaoClassifier) {
+		classifier.AddExample("positive", "loved the game last night")
+		classifier.AddExample("positive", "great game, loved it")
+		classifier.AddExample("positive", "what a great concert")
+		classifier.AddExample("negative", "the service was terrible")
+		classifier.AddExample("negative", "terrible game, hated it")
+	}
+
+	t.Run("Stores examples added through the API once. ", func(t *testing.T) {
+		classifier := newTestClassifier(t)
+
+		classifier.AddExample("positive", "great game")
+		classifier.AddExample("positive", "great game")
+
+		if _, err := classifier.AddExample("", "great game"); err == nil {
+			t.Errorf("Expected an error for an empty label, got nil")
+		}
+
+		if examples := classifier.GetSavableModel().Examples; !reflect.DeepEqual(examples, []Example{{Text: "great game", Label: "positive"}}) {
+			t.Errorf("Expected a single example, got %v", examples)
+		}
+	})
+
+	t.Run("Train stores examples from the dataset without the target column. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{
+			TrainingDatasetPath: "../datasets/student_performance.csv",
+			TargetColumn:        "ParentalSupport",
+			PromptSampleSize:    1,
+			FewShotK:            3,
+			ExamplesPerClass:    2,
+		})
+
+		if err := classifier.Train(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		counts := map[Label]int{}
+
+		for _, example := range classifier.GetSavableModel().Examples {
+			counts[example.Label]++
+
+			if strings.Contains(example.Text, "ParentalSupport") || !strings.Contains(example.Text, `"StudyHoursPerWeek"`) {
+				t.Errorf("Expected the row without the target column, got %v", example.Text)
+			}
+		}
+
+		if !reflect.DeepEqual(counts, map[Label]int{"High": 2, "Medium": 2, "Low": 2}) {
+			t.Errorf("Expected 2 examples per class, got %v", counts)
+		}
+	})
+
+	t.Run("Nearest selection picks the most similar examples. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{FewShotK: 2, FewShotStrategy: FewShotNearest})
+		addExamples(classifier)
+
+		examples := classifier.selectExamples("terrible game, awful service")
+
+		if len(examples) != 2 || examples[0].Label != "negative" || examples[1].Label != "negative" {
+			t.Errorf("Expected the two negative examples, got %v", examples)
+		}
+	})
+
+	t.Run("Per-class selection lets the classes take turns. ", func(t *testing.T) {
+		for _, strategy := range []FewShotStrategy{FewShotRandom, FewShotRepresentative} {
+			classifier := newTestClassifier(t, TaoClassifierOptions{FewShotK: 3, FewShotStrategy: strategy})
+			addExamples(classifier)
+
+			examples := classifier.selectExamples("great game")
+
+			if len(examples) != 3 || examples[0].Label != "negative" || examples[1].Label != "positive" || examples[2].Label != "negative" {
+				t.Errorf("Expected negative, positive, negative with %v, got %v", strategy, examples)
+			}
+		}
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{FewShotK: 1, FewShotStrategy: FewShotRepresentative})
+		classifier.AddExample("positive", "what a great concert")
+		classifier.AddExample("positive", "great game, loved it")
+		classifier.AddExample("positive", "loved the game last night")
+
+		if examples := classifier.selectExamples(""); examples[0].Text != "great game, loved it" {
+			t.Errorf("Expected the example closest to the rest of its class, got %v", examples)
+		}
+
+		// the order is updated as examples change
+		classifier.RemoveExample("positive", "great game, loved it")
+		classifier.AddExample("positive", "loved the concert last night")
+
+		if examples := classifier.selectExamples(""); examples[0].Text != "loved the concert last night" {
+			t.Errorf("Expected the new most representative example, got %v", examples)
+		}
+	})
+
+	t.Run("Injects the examples into the prediction prompt and persists them. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{ModelId: "test_few_shot_model", FewShotK: 2, FewShotStrategy: FewShotNearest})
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"positive": {"positive sentiment"},
+			"negative": {"negative sentiment"},
+		})
+		addExamples(classifier)
+
+		if _, err := classifier.SaveModel(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		provider := NewFakeProvider(`{"predicted_class": "negative", "probability": 0.9}`)
+		loadedClassifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, FewShotK: 2, FewShotStrategy: FewShotNearest})
+
+		if _, err := loadedClassifier.LoadModel("test_few_shot_model"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, err := loadedClassifier.PredictOne("the service was slow and terrible"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		systemPrompt := provider.Requests()[0].SystemPrompt()
+
+		if !strings.Contains(systemPrompt, "Labeled examples\nText: the service was terrible\nClass: negative\n") {
+			t.Errorf("Expected the nearest example in the prompt, got %v", systemPrompt)
+		}
+
+		if strings.Count(systemPrompt, "Text: ") != 2 {
+			t.Errorf("Expected 2 examples in the prompt, got %v", systemPrompt)
+		}
+	})
+
+	t.Run("Lists, removes and clears examples, also with their labels. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{FewShotK: 5})
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"positive": {"positive sentiment"},
+			"negative": {"negative sentiment"},
+		})
+		addExamples(classifier)
+
+		if _, err := classifier.RemoveExample("positive", "what a great concert"); err != nil || len(classifier.GetExamples()) != 4 {
+			t.Errorf("Expected 4 examples after removing one, got %v (%v)", classifier.GetExamples(), err)
+		}
+
+		if _, err := classifier.RemoveExample("positive", "what a great concert"); err == nil {
+			t.Errorf("Expected an error for a missing example, got nil")
+		}
+
+		classifier.RemovePrompt("negative")
+
+		for _, example := range classifier.selectExamples("") {
+			if example.Label == "negative" {
+				t.Errorf("Expected no examples of the removed label, got %v", example)
+			}
+		}
+
+		if examples := classifier.GetSavableModel().Examples; len(examples) != 2 {
+			t.Errorf("Expected the 2 positive examples to be saved, got %v", examples)
+		}
+
+		classifier.ClearExamples()
+
+		if examples := classifier.GetExamples(); len(examples) != 0 {
+			t.Errorf("Expected no examples, got %v", examples)
+		}
+
+		addExamples(classifier)
+		classifier.ClearPrompts()
+
+		if examples := classifier.GetExamples(); len(examples) != 0 {
+			t.Errorf("Expected ClearPrompts to remove the examples, got %v", examples)
+		}
+	})
+
+	t.Run("Rejects unknown strategies. ", func(t *testing.T) {
+		var configErr *ConfigError
+
+		if _, err := NewTaoClassifier(TaoClassifierOptions{Provider: NewFakeProvider(), FewShotStrategy: "best"}); !errors.As(err, &configErr) {
+			t.Errorf("Expected a *ConfigError, got %v", err)
+		}
+	})
+}
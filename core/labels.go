@@ -0,0 +1,164 @@
+package core
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// LabelPolicy decides what happens when the model predicts a class that isn't one of the known labels.
+type LabelPolicy string
+
+const (
+	LabelPolicyReject  LabelPolicy = "reject"  // fail the prediction with a *ValidationError
+	LabelPolicyRetry   LabelPolicy = "retry"   // ask the model again with a corrective message, then reject
+	LabelPolicyUnknown LabelPolicy = "unknown" // map the prediction to the unknown label
+)
+
+const (
+	defaultUnknownLabel    = "unknown"
+	defaultMaxLabelRetries = 1
+)
+
+// MatchLabel resolves a predicted class to one of labels. Matching ignores case and surrounding or repeated
+// whitespace, coerces numbers (e.g. 1 or 1.0 for the label "1") and falls back to the closest label by edit
+// distance for small typos. It returns false when no label is close enough.
+func MatchLabel(predicted interface{}, labels []Label) (Label, bool) {
+	value, ok := labelString(predicted)
+
+	if !ok {
+		return "", false
+	}
+
+	normalized := normalizeLabel(value)
+
+	if normalized == "" {
+		return "", false
+	}
+
+	for _, label := range labels {
+		if normalizeLabel(label) == normalized {
+			return label, true
+		}
+	}
+
+	// numeric classes can come back as 1.0 for "1"
+	if number, err := strconv.ParseFloat(normalized, 64); err == nil {
+		for _, label := range labels {
+			labelNumber, err := strconv.ParseFloat(normalizeLabel(label), 64)
+
+			if err == nil && labelNumber == number {
+				return label, true
+			}
+		}
+
+		// a different number is a different class, not a typo
+		return "", false
+	}
+
+	best := ""
+	bestDistance := -1
+	tie := false
+
+	for _, label := range labels {
+		distance := levenshtein(normalized, normalizeLabel(label))
+
+		switch {
+		case bestDistance == -1 || distance < bestDistance:
+			best, bestDistance, tie = label, distance, false
+		case distance == bestDistance:
+			tie = true
+		}
+	}
+
+	if bestDistance == -1 || tie || bestDistance > max(1, len([]rune(normalized))/3) {
+		return "", false
+	}
+
+	return best, true
+}
+
+// labelString converts the predicted_class value decoded from JSON to a string.
+func labelString(predicted interface{}) (string, bool) {
+	switch value := predicted.(type) {
+	case string:
+		return value, true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	case int:
+		return strconv.Itoa(value), true
+	case bool:
+		return strconv.FormatBool(value), true
+	case nil:
+		return "", false
+	}
+
+	return fmt.Sprint(predicted), true
+}
+
+func normalizeLabel(label string) string {
+	label = strings.Trim(strings.TrimSpace(label), `"'.`)
+
+	return strings.ToLower(strings.Join(strings.Fields(label), " "))
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a string, b string) int {
+	ar, br := []rune(a), []rune(b)
+	previous := make([]int, len(br)+1)
+	current := make([]int, len(br)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(ar); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(br)]
+}
+
+// validateLabel replaces the predicted class with the matching known label. Predictions that don't match
+// are mapped to the unknown label with LabelPolicyUnknown and fail with a *ValidationError otherwise, which
+// keeps raw, the model output result was parsed from.
+func (c *TaoClassifier) validateLabel(result ClassificationResult, raw string) (ClassificationResult, error) {
+	labels, _ := c.GetAvailableLabels()
+
+	if len(labels) == 0 {
+		return result, nil
+	}
+
+	label, ok := MatchLabel(result.PredictedClass, labels)
+
+	if ok {
+		result.PredictedClass = label
+		return result, nil
+	}
+
+	if c.labelPolicy == LabelPolicyUnknown {
+		result.PredictedClass = c.unknownLabel
+		return result, nil
+	}
+
+	return result, &ValidationError{Value: result.PredictedClass, Raw: raw, Err: ErrUnknownLabel}
+}
+
+// labelCorrection is the follow-up message sent with LabelPolicyRetry after an unknown label.
+func (c *TaoClassifier) labelCorrection(predicted interface{}) string {
+	labels, _ := c.GetAvailableLabels()
+
+	return fmt.Sprintf(`"%v" is not one of the available labels. Respond again in JSON with { predicted_class: <class>, "probability": <probability>, "distribution": { <class>: <probability> } } using exactly one of these labels: %s`, predicted, strings.Join(labels, ", "))
+}
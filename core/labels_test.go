@@ -0,0 +1,147 @@
+package core
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMatchLabel(t *testing.T) {
+	t.Run("Matches labels ignoring case, whitespace and number formatting. ", func(t *testing.T) {
+		labels := []Label{"cat", "dog", "Very High", "1", "2"}
+
+		cases := []struct {
+			predicted interface{}
+			expected  Label
+		}{
+			{"cat", "cat"},
+			{" CAT ", "cat"},
+			{"very   high", "Very High"},
+			{"Dog.", "dog"},
+			{float64(1), "1"},
+			{"2.0", "2"},
+			{"very hihg", "Very High"},
+		}
+
+		for _, c := range cases {
+			label, ok := MatchLabel(c.predicted, labels)
+
+			if !ok || label != c.expected {
+				t.Errorf("Expected %v for %v, got %v (%v)", c.expected, c.predicted, label, ok)
+			}
+		}
+	})
+
+	t.Run("Doesn't match unrelated classes or other numbers. ", func(t *testing.T) {
+		labels := []Label{"cat", "dog", "1", "2"}
+
+		for _, predicted := range []interface{}{"giraffe", float64(3), "", nil} {
+			if label, ok := MatchLabel(predicted, labels); ok {
+				t.Errorf("Expected no match for %v, got %v", predicted, label)
+			}
+		}
+	})
+}
+
+func TestLabelPolicy(t *testing.T) {
+	prompts := map[Label][]LabelDescription{
+		"cat": {"meows"},
+		"dog": {"barks"},
+	}
+
+	t.Run("Rejects unknown labels with a ValidationError by default. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: NewFakeProvider(`{"predicted_class": "giraffe", "probability": 0.9}`)})
+		classifier.PromptTrain(prompts)
+
+		_, err := classifier.PredictOne("Meow")
+
+		var validationErr *ValidationError
+
+		if !errors.As(err, &validationErr) || !errors.Is(err, ErrUnknownLabel) {
+			t.Errorf("Expected a ValidationError, got %v", err)
+		}
+
+		if CategorizeError(err) != ErrorCategoryValidation {
+			t.Errorf("Expected the validation category, got %v", CategorizeError(err))
+		}
+	})
+
+	t.Run("Maps unknown labels to the unknown label. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{
+			Provider:     NewFakeProvider(`{"predicted_class": "giraffe", "probability": 0.9}`),
+			LabelPolicy:  LabelPolicyUnknown,
+			UnknownLabel: "other",
+		})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Meow")
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if result.PredictedClass != "other" {
+			t.Errorf("Expected other, got %v", result.PredictedClass)
+		}
+	})
+
+	t.Run("Retries with a corrective message. ", func(t *testing.T) {
+		provider := NewFakeProvider(
+			`{"predicted_class": "giraffe", "probability": 0.9}`,
+			`{"predicted_class": "Cat", "probability": 0.8}`,
+		)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, LabelPolicy: LabelPolicyRetry})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Meow")
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if result.PredictedClass != "cat" {
+			t.Errorf("Expected cat, got %v", result.PredictedClass)
+		}
+
+		requests := provider.Requests()
+
+		if len(requests) != 2 {
+			t.Fatalf("Expected 2 requests, got %v", len(requests))
+		}
+
+		messages := requests[1].Messages
+
+		if messages[2].Role != RoleAssistant || !strings.Contains(requests[1].LastUserMessage(), "cat, dog") {
+			t.Errorf("Expected the previous answer and a correction listing the labels, got %+v", messages)
+		}
+	})
+
+	t.Run("Validates labels in batched prompts. ", func(t *testing.T) {
+		provider := NewFakeProvider(`[{"id": "0", "predicted_class": "DOG", "probability": 0.9}, {"id": "1", "predicted_class": "giraffe", "probability": 0.9}]`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, BatchSize: 2})
+		classifier.PromptTrain(prompts)
+
+		batch, err := classifier.PredictMany([]string{"Woof", "Hmm"})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if batch.Items[0].Result.PredictedClass != "dog" {
+			t.Errorf("Expected dog, got %v", batch.Items[0].Result.PredictedClass)
+		}
+
+		if batch.Items[1].ErrorCategory != ErrorCategoryValidation {
+			t.Errorf("Expected a validation error, got %v", batch.Items[1].Err)
+		}
+	})
+	t.Run("Rejects unknown label policies. ", func(t *testing.T) {
+		var configErr *ConfigError
+
+		if _, err := NewTaoClassifier(TaoClassifierOptions{Provider: NewFakeProvider(), LabelPolicy: "ignore"}); !errors.As(err, &configErr) || configErr.Field != "LabelPolicy" {
+			t.Errorf("Expected a LabelPolicy *ConfigError, got %v", err)
+		}
+	})
+}
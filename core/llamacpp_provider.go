@@ -0,0 +1,38 @@
+package core
+
+import "net/http"
+
+type LlamaCppProviderOptions struct {
+	BaseURL    string       // defaults to http://localhost:8080/v1/
+	Model      string       // llama.cpp serves a single model, so the name is informational; defaults to "local"
+	APIKey     string       // only needed when llama-server is started with --api-key
+	HTTPClient *http.Client // defaults to http.DefaultClient
+}
+
+// NewLlamaCppProvider returns a provider for llama.cpp's OpenAI-compatible server (llama-server).
+func NewLlamaCppProvider(opts ...LlamaCppProviderOptions) (*OpenAIProvider, error) {
+	options := LlamaCppProviderOptions{}
+
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if options.BaseURL == "" {
+		options.BaseURL = "http://localhost:8080/v1/"
+	}
+
+	if options.Model == "" {
+		options.Model = "local"
+	}
+
+	if options.APIKey == "" {
+		options.APIKey = "no-key"
+	}
+
+	return NewOpenAIProvider(OpenAIProviderOptions{
+		APIKey:     options.APIKey,
+		Model:      options.Model,
+		BaseURL:    options.BaseURL,
+		HTTPClient: options.HTTPClient,
+	})
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLlamaCppProviderChat(t *testing.T) {
+	t.Run("Uses the OpenAI-compatible chat completions endpoint. ", func(t *testing.T) {
+		var received map[string]any
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/chat/completions" {
+				t.Errorf("Expected /v1/chat/completions, got %v", r.URL.Path)
+			}
+
+			json.NewDecoder(r.Body).Decode(&received)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id": "1", "object": "chat.completion", "created": 1, "model": "local", "choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "positive"}}]}`))
+		}))
+		defer server.Close()
+
+		provider, err := NewLlamaCppProvider(LlamaCppProviderOptions{BaseURL: server.URL + "/v1", Model: "qwen2.5-7b"})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		response, err := provider.Chat(context.Background(), ChatRequest{
+			Messages: []Message{{Role: RoleUser, Content: "Great game last night!"}},
+		})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if response.Content != "positive" {
+			t.Errorf("Expected positive, got %v", response.Content)
+		}
+
+		if received["model"] != "qwen2.5-7b" {
+			t.Errorf("Expected model qwen2.5-7b, got %v", received["model"])
+		}
+	})
+
+	t.Run("Sends the response schema as a json_schema response format. ", func(t *testing.T) {
+		var received map[string]any
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&received)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id": "1", "object": "chat.completion", "created": 1, "model": "local", "choices": [{"index": 0, "finish_reason": "stop", "message": {"role": "assistant", "content": "{\"predicted_class\": \"positive\"}"}}]}`))
+		}))
+		defer server.Close()
+
+		provider, err := NewLlamaCppProvider(LlamaCppProviderOptions{BaseURL: server.URL + "/v1"})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		_, err = provider.Chat(context.Background(), ChatRequest{
+			Messages: []Message{{Role: RoleUser, Content: "Great game last night!"}},
+			ResponseSchema: &ResponseSchema{
+				Name:   "classification",
+				Schema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{"predicted_class": map[string]interface{}{"type": "string", "enum": []string{"negative", "positive"}}}},
+				Strict: true,
+			},
+		})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		responseFormat, _ := received["response_format"].(map[string]any)
+		jsonSchema, _ := responseFormat["json_schema"].(map[string]any)
+
+		if responseFormat["type"] != "json_schema" || jsonSchema["name"] != "classification" || jsonSchema["strict"] != true || jsonSchema["schema"] == nil {
+			t.Errorf("Expected a strict json_schema response format, got %v", received["response_format"])
+		}
+	})
+
+	t.Run("Requests and returns token logprobs. ", func(t *testing.T) {
+		var received map[string]any
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&received)
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"id": "1", "object": "chat.completion", "created": 1, "model": "local", "choices": [{"index": 0, "finish_reason": "length", "message": {"role": "assistant", "content": "A"}, "logprobs": {"content": [{"token": "A", "logprob": -0.1, "bytes": null, "top_logprobs": [{"token": "A", "logprob": -0.1, "bytes": null}, {"token": "B", "logprob": -2.3, "bytes": null}]}]}}]}`))
+		}))
+		defer server.Close()
+
+		provider, err := NewLlamaCppProvider(LlamaCppProviderOptions{BaseURL: server.URL + "/v1"})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		response, err := provider.Chat(context.Background(), ChatRequest{
+			Messages:    []Message{{Role: RoleUser, Content: "Great game last night!"}},
+			MaxTokens:   1,
+			TopLogprobs: 2,
+		})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if received["logprobs"] != true || received["top_logprobs"] != float64(2) || received["max_tokens"] != float64(1) {
+			t.Errorf("Expected logprobs, top_logprobs and max_tokens, got %v", received)
+		}
+
+		if len(response.Logprobs) != 1 || len(response.Logprobs[0].TopLogprobs) != 2 || response.Logprobs[0].TopLogprobs[1].Token != "B" {
+			t.Errorf("Expected the token logprobs, got %+v", response.Logprobs)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ScoringMode decides where ClassificationResult.Probability comes from.
+type ScoringMode string
+
+const (
+	ScoringSelfReported ScoringMode = "self_reported" // the probability the model writes into its JSON answer
+	ScoringLogprobs     ScoringMode = "logprobs"      // a distribution over all labels from the token log probabilities
+)
+
+// OpenAI returns at most 20 alternatives per token, so one label code per alternative
+const maxLogprobLabels = 20
+
+// labelCodes assigns the single-token codes A, B, C, ... to the sorted labels.
+func labelCodes(labels []Label) map[string]Label {
+	codes := map[string]Label{}
+
+	for index, label := range labels {
+		codes[string(rune('A'+index))] = label
+	}
+
+	return codes
+}
+
+// predictLogprobs asks the model for the code of the most likely label and reads the probability of every
+// label off the log probabilities of the first generated token.
+func (c *TaoClassifier) predictLogprobs(ctx context.Context, text string) (ClassificationResult, error) {
+	labels, _ := c.GetAvailableLabels()
+
+	if len(labels) == 0 {
+		return ClassificationResult{Label: "", Probability: -1}, fmt.Errorf("PredictOne: no labels found. Call Train() or PromptTrain() first. ")
+	}
+
+	if len(labels) > maxLogprobLabels {
+		return ClassificationResult{Label: "", Probability: -1}, &ConfigError{Field: "ScoringMode", Err: fmt.Errorf("logprob scoring supports at most %d labels, got %d", maxLogprobLabels, len(labels))}
+	}
+
+	codes := labelCodes(labels)
+
+	codeList := ""
+	for index, label := range labels {
+		codeList += fmt.Sprintf("%c: %s\n", 'A'+index, label)
+	}
+
+	systemPrompt := fmt.Sprintf(`You are an AI assistant that performs classification.
+	You will be given a map of predicted classes and their corresponding descriptions.
+	Use this information to classify the given data point.
+	Each class has a code. Respond with only the code of the most likely class, e.g. A.
+	Codes:
+%s
+	Context: %s\n`, codeList, c.predictionContext(text))
+
+	userPrompt := fmt.Sprintf(`Classify the following text: "%s"`, text)
+
+	response, err := c.ai.complete(ctx, ChatRequest{
+		Messages: []Message{
+			{Role: RoleSystem, Content: systemPrompt},
+			{Role: RoleUser, Content: userPrompt},
+		},
+		Seed:        1,
+		MaxTokens:   1,
+		TopLogprobs: maxLogprobLabels,
+	})
+
+	if err != nil {
+		return ClassificationResult{Label: "", Probability: -1}, fmt.Errorf("PredictOne: failed to generate completions: %w", err)
+	}
+
+	if c.verbose {
+		fmt.Println("System Prompt: ", systemPrompt)
+		fmt.Println("Prompt: ", userPrompt)
+		fmt.Println("Generated Text: ", response.Content)
+	}
+
+	if len(response.Logprobs) == 0 {
+		return ClassificationResult{Label: "", Probability: -1}, &ConfigError{Field: "ScoringMode", Err: ErrNoLogprobs}
+	}
+
+	distribution := labelDistribution(response.Logprobs[0], codes)
+
+	if distribution == nil {
+		return ClassificationResult{Label: "", Probability: -1}, &ParseError{Raw: response.Content, Err: fmt.Errorf("no label code among the most likely tokens")}
+	}
+
+	predicted := labels[0]
+
+	for _, label := range labels {
+		if distribution[label] > distribution[predicted] {
+			predicted = label
+		}
+	}
+
+	return ClassificationResult{
+		Label:          c.targetColumn,
+		PredictedClass: predicted,
+		Probability:    distribution[predicted],
+		Distribution:   distribution,
+	}, nil
+}
+
+// labelDistribution sums the probability of the tokens spelling each label code (e.g. "A" and " A") and
+// normalizes over the labels. It returns nil when none of the tokens is a label code.
+func labelDistribution(token TokenLogprob, codes map[string]Label) map[Label]float64 {
+	logprobs := map[string]float64{token.Token: token.Logprob}
+
+	for _, top := range token.TopLogprobs {
+		logprobs[top.Token] = top.Logprob
+	}
+
+	distribution := map[Label]float64{}
+	total := 0.0
+
+	for _, label := range codes {
+		distribution[label] = 0
+	}
+
+	for token, logprob := range logprobs {
+		label, ok := codes[strings.TrimSpace(token)]
+
+		if !ok {
+			continue
+		}
+
+		probability := math.Exp(logprob)
+		distribution[label] += probability
+		total += probability
+	}
+
+	if total == 0 {
+		return nil
+	}
+
+	for label := range distribution {
+		distribution[label] /= total
+	}
+
+	return distribution
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLogprobScoring(t *testing.T) {
+	prompts := map[Label][]LabelDescription{
+		"cat":  {"meows"},
+		"dog":  {"barks"},
+		"bird": {"sings"},
+	}
+
+	t.Run("Computes a distribution over all labels from the label codes. ", func(t *testing.T) {
+		// codes follow the sorted labels: A = bird, B = cat, C = dog
+		provider := NewScriptedFakeProvider(FakeResponse{
+			Content: "B",
+			Logprobs: []TokenLogprob{{
+				Token:   "B",
+				Logprob: math.Log(0.6),
+				TopLogprobs: []TopLogprob{
+					{Token: "B", Logprob: math.Log(0.6)},
+					{Token: " B", Logprob: math.Log(0.1)},
+					{Token: "C", Logprob: math.Log(0.2)},
+					{Token: "The", Logprob: math.Log(0.1)},
+				},
+			}},
+		})
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, ScoringMode: ScoringLogprobs, TargetColumn: "animal"})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Meow")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.PredictedClass != "cat" || math.Abs(result.Probability-0.7/0.9) > 1e-9 {
+			t.Errorf("Expected cat with probability %v, got %v with %v", 0.7/0.9, result.PredictedClass, result.Probability)
+		}
+
+		if len(result.Distribution) != 3 || result.Distribution["bird"] != 0 || math.Abs(result.Distribution["dog"]-0.2/0.9) > 1e-9 {
+			t.Errorf("Expected a distribution over the 3 labels, got %v", result.Distribution)
+		}
+
+		if result.Label != "animal" {
+			t.Errorf("Expected label animal, got %v", result.Label)
+		}
+
+		request := provider.Requests()[0]
+
+		if request.TopLogprobs != maxLogprobLabels || request.MaxTokens != 1 || !strings.Contains(request.SystemPrompt(), "B: cat") {
+			t.Errorf("Expected a one token request with logprobs and label codes, got %+v", request)
+		}
+	})
+
+	t.Run("Returns a ConfigError when the provider has no logprobs. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: NewFakeProvider("B"), ScoringMode: ScoringLogprobs})
+		classifier.PromptTrain(prompts)
+
+		_, err := classifier.PredictOne("Meow")
+
+		var configErr *ConfigError
+
+		if !errors.As(err, &configErr) || !errors.Is(err, ErrNoLogprobs) {
+			t.Errorf("Expected a ConfigError wrapping ErrNoLogprobs, got %v", err)
+		}
+	})
+
+	t.Run("Returns a ConfigError for more labels than logprobs. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: NewFakeProvider(), ScoringMode: ScoringLogprobs})
+
+		for index := range maxLogprobLabels + 1 {
+			classifier.AddPrompt(fmt.Sprint("label", index), "a description")
+		}
+
+		_, err := classifier.PredictOne("Meow")
+
+		var configErr *ConfigError
+
+		if !errors.As(err, &configErr) {
+			t.Errorf("Expected a ConfigError, got %v", err)
+		}
+	})
+	t.Run("Rejects unknown scoring modes. ", func(t *testing.T) {
+		var configErr *ConfigError
+
+		if _, err := NewTaoClassifier(TaoClassifierOptions{Provider: NewFakeProvider(), ScoringMode: "verbalized"}); !errors.As(err, &configErr) || configErr.Field != "ScoringMode" {
+			t.Errorf("Expected a ScoringMode *ConfigError, got %v", err)
+		}
+	})
+}
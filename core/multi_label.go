@@ -0,0 +1,179 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	defaultMultiLabelThreshold = 0.5
+	defaultLabelSeparator      = "|"
+)
+
+type multiLabelPrediction struct {
+	Labels map[string]float64 `json:"labels"`
+}
+
+func (multiLabelPrediction) requiredJsonKeys() []string {
+	return []string{"labels"}
+}
+
+// splitLabels splits a target column value such as "billing|bug" into its labels.
+func splitLabels(value string, separator string) []Label {
+	labels := []Label{}
+
+	for _, label := range strings.Split(value, separator) {
+		label = strings.TrimSpace(label)
+
+		if label != "" && !Contains(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+
+	return labels
+}
+
+// rowClasses returns the classes of row: the trimmed target value, split into single labels in multi-label
+// mode. A row without a target value has no classes.
+func (c *TaoClassifier) rowClasses(row RowItem) []Label {
+	value := strings.TrimSpace(row[c.targetColumn])
+
+	if value == "" {
+		return nil
+	}
+
+	if c.multiLabel {
+		return splitLabels(value, c.labelSeparator)
+	}
+
+	return []Label{value}
+}
+
+// datasetClasses returns the classes found in the target column in order of appearance, see rowClasses.
+func (c *TaoClassifier) datasetClasses() []Label {
+	labels := []Label{}
+
+	for _, row := range c.dataset {
+		for _, label := range c.rowClasses(row) {
+			if !Contains(labels, label) {
+				labels = append(labels, label)
+			}
+		}
+	}
+
+	return labels
+}
+
+// predictMultiLabel asks for an independent probability per label and predicts every label at or above
+// c.multiLabelThreshold. PredictedClass joins the predicted labels with c.labelSeparator like the dataset
+// does, and Probability is the lowest probability among them, or 1 minus the highest when none is predicted.
+func (c *TaoClassifier) predictMultiLabel(ctx context.Context, text string) (ClassificationResult, error) {
+	labels, _ := c.GetAvailableLabels()
+
+	if len(labels) == 0 {
+		return ClassificationResult{Label: "", Probability: -1}, fmt.Errorf("PredictOne: no labels found. Call Train() or PromptTrain() first. ")
+	}
+
+	systemPrompt := fmt.Sprintf(`You are an AI assistant that performs multi-label classification.
+	You will be given a map of classes and their corresponding descriptions.
+	A data point can belong to any number of the classes, including none.
+	For every class, estimate the probability that the data point belongs to it, independently of the other classes.
+	Respond in JSON with { "labels": { <class>: <probability> } }, with a probability for every class.
+	Context: %s\n`, c.predictionContext(text))
+
+	userPrompt := fmt.Sprintf(`Classify the following text: "%s"`, text)
+
+	response, err := c.ai.GenerateTextContext(ctx, userPrompt, GenerateTextOptions{Verbose: false, System: systemPrompt, ResponseSchema: c.multiLabelSchema()})
+
+	if err != nil {
+		return ClassificationResult{Label: "", Probability: -1}, err
+	}
+
+	if c.verbose {
+		fmt.Println("System Prompt: ", systemPrompt)
+		fmt.Println("Prompt: ", userPrompt)
+		fmt.Println("Generated Text: ", response)
+	}
+
+	prediction, err := CleanGPTJson[multiLabelPrediction](response)
+
+	if err != nil {
+		return ClassificationResult{Label: "", Probability: -1}, err
+	}
+
+	probabilities := map[Label]float64{}
+
+	for _, label := range labels {
+		probabilities[label] = 0
+	}
+
+	// unknown labels are dropped, each known label is independent so there is nothing to map them to
+	for key, probability := range prediction.Labels {
+		if label, ok := MatchLabel(key, labels); ok {
+			probabilities[label] = max(probabilities[label], min(1, max(0, probability)))
+		}
+	}
+
+	predicted := []Label{}
+
+	for _, label := range labels {
+		if probabilities[label] >= c.multiLabelThreshold {
+			predicted = append(predicted, label)
+		}
+	}
+
+	sort.SliceStable(predicted, func(i, j int) bool {
+		return probabilities[predicted[i]] > probabilities[predicted[j]]
+	})
+
+	probability := 1.0
+
+	if len(predicted) > 0 {
+		for _, label := range predicted {
+			probability = min(probability, probabilities[label])
+		}
+	} else {
+		for _, label := range labels {
+			probability = min(probability, 1-probabilities[label])
+		}
+	}
+
+	return ClassificationResult{
+		Label:              c.targetColumn,
+		PredictedClass:     strings.Join(predicted, c.labelSeparator),
+		Probability:        probability,
+		PredictedLabels:    predicted,
+		LabelProbabilities: probabilities,
+	}, nil
+}
+
+// multiLabelSchema is the structured output of multi-label predictions: { labels: { <label>: <probability> } }.
+func (c *TaoClassifier) multiLabelSchema() *ResponseSchema {
+	labels, _ := c.GetAvailableLabels()
+
+	scores := map[string]interface{}{}
+
+	for _, label := range labels {
+		scores[label] = map[string]interface{}{"type": "number"}
+	}
+
+	return &ResponseSchema{
+		Name: "multi_label_classification",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"labels": map[string]interface{}{
+					"type":                 "object",
+					"properties":           scores,
+					"required":             labels,
+					"additionalProperties": false,
+				},
+			},
+			"required":             []string{"labels"},
+			"additionalProperties": false,
+		},
+		Strict: true,
+	}
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMultiLabel(t *testing.T) {
+	prompts := map[Label][]LabelDescription{
+		"billing": {"mentions invoices or payments"},
+		"bug":     {"describes something broken"},
+		"feature": {"asks for something new"},
+	}
+
+	t.Run("TopK ranks the labels by their probabilities. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"labels": {"billing": 0.9, "bug": 0.7, "feature": 0.2}}`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, MultiLabel: true})
+		classifier.PromptTrain(prompts)
+
+		scores, err := classifier.TopK("I was charged twice because the checkout page crashed", 2)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !reflect.DeepEqual(scores, []LabelScore{{Label: "billing", Score: 0.9}, {Label: "bug", Score: 0.7}}) {
+			t.Errorf("Expected billing then bug, got %v", scores)
+		}
+	})
+
+	t.Run("Predicts every label above the threshold. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"labels": {"billing": 0.9, "Bug": 0.7, "feature": 0.2}}`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, MultiLabel: true, MultiLabelThreshold: 0.6})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("I was charged twice because the checkout page crashed")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !reflect.DeepEqual(result.PredictedLabels, []Label{"billing", "bug"}) {
+			t.Errorf("Expected billing and bug, got %v", result.PredictedLabels)
+		}
+
+		if result.PredictedClass != "billing|bug" || result.Probability != 0.7 {
+			t.Errorf("Expected billing|bug with probability 0.7, got %v with %v", result.PredictedClass, result.Probability)
+		}
+
+		if result.LabelProbabilities["feature"] != 0.2 {
+			t.Errorf("Expected an independent probability for feature, got %v", result.LabelProbabilities)
+		}
+	})
+
+	t.Run("Predicts no labels when none reaches the threshold. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"labels": {"billing": 0.1, "bug": 0.3, "feature": 0.2}}`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, MultiLabel: true})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Thanks!")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(result.PredictedLabels) != 0 || result.PredictedClass != "" || result.Probability != 0.7 {
+			t.Errorf("Expected no labels with probability 0.7, got %v with %v", result.PredictedLabels, result.Probability)
+		}
+	})
+
+	t.Run("Splits label combinations from the dataset and PromptTrain. ", func(t *testing.T) {
+		datasetPath := filepath.Join(t.TempDir(), "tickets.csv")
+
+		err := os.WriteFile(datasetPath, []byte("text,category\ncharged twice,billing|bug\napp crashes,bug\nadd dark mode,feature\n"), 0644)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{TrainingDatasetPath: datasetPath, TargetColumn: "category", MultiLabel: true})
+
+		labels, _ := classifier.GetAvailableLabels()
+		sort.Strings(labels)
+
+		if !reflect.DeepEqual(labels, []Label{"billing", "bug", "feature"}) {
+			t.Errorf("Expected billing, bug and feature, got %v", labels)
+		}
+
+		classifier.PromptTrain(map[Label][]LabelDescription{"billing|bug": {"a payment failed because of an error"}})
+
+		if len(classifier.GetPrompts()["billing"]) != 1 || len(classifier.GetPrompts()["bug"]) != 1 {
+			t.Errorf("Expected the description on both labels, got %v", classifier.GetPrompts())
+		}
+	})
+
+	t.Run("Persists the multi-label settings. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{ModelId: "test_multi_label_model", MultiLabel: true, MultiLabelThreshold: 0.4, LabelSeparator: ";"})
+		classifier.PromptTrain(prompts)
+
+		_, err := classifier.SaveModel()
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		loadedClassifier := newTestClassifier(t)
+
+		_, err = loadedClassifier.LoadModel("test_multi_label_model")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		model := loadedClassifier.GetSavableModel()
+
+		if !model.MultiLabel || model.MultiLabelThreshold != 0.4 || model.LabelSeparator != ";" {
+			t.Errorf("Expected the multi-label settings to be loaded, got %+v", model)
+		}
+	})
+
+	t.Run("Rejects logprob scoring. ", func(t *testing.T) {
+		_, err := NewTaoClassifier(TaoClassifierOptions{Provider: NewFakeProvider(), MultiLabel: true, ScoringMode: ScoringLogprobs})
+
+		if err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// OllamaProvider talks to a locally hosted Ollama server through its native /api/chat endpoint,
+// so data never leaves the network.
+type OllamaProvider struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+type OllamaProviderOptions struct {
+	BaseURL    string       // defaults to OLLAMA_HOST or http://localhost:11434
+	Model      string       // defaults to llama3.1
+	HTTPClient *http.Client // defaults to http.DefaultClient
+}
+
+type ollamaMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Stream   bool            `json:"stream"`
+	Format   any             `json:"format,omitempty"` // a JSON Schema for structured outputs
+	Options  map[string]any  `json:"options,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message    ollamaMessage `json:"message"`
+	Done       bool          `json:"done"`
+	DoneReason string        `json:"done_reason"`
+	Error      string        `json:"error"`
+}
+
+func NewOllamaProvider(opts ...OllamaProviderOptions) *OllamaProvider {
+	options := OllamaProviderOptions{}
+
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if options.BaseURL == "" {
+		options.BaseURL = os.Getenv("OLLAMA_HOST")
+	}
+
+	if options.BaseURL == "" {
+		options.BaseURL = "http://localhost:11434"
+	}
+
+	if !strings.HasPrefix(options.BaseURL, "http://") && !strings.HasPrefix(options.BaseURL, "https://") {
+		// OLLAMA_HOST is commonly set without a scheme, e.g. 127.0.0.1:11434
+		options.BaseURL = "http://" + options.BaseURL
+	}
+
+	if options.Model == "" {
+		options.Model = "llama3.1"
+	}
+
+	if options.HTTPClient == nil {
+		options.HTTPClient = http.DefaultClient
+	}
+
+	return &OllamaProvider{
+		baseURL:    strings.TrimSuffix(options.BaseURL, "/"),
+		model:      options.Model,
+		httpClient: options.HTTPClient,
+	}
+}
+
+func (p *OllamaProvider) Chat(ctx context.Context, request ChatRequest) (ChatResponse, error) {
+	messages := []ollamaMessage{}
+
+	for _, message := range request.Messages {
+		messages = append(messages, ollamaMessage{Role: message.Role, Content: message.Content})
+	}
+
+	chatRequest := ollamaChatRequest{
+		Model:    p.model,
+		Messages: messages,
+		Stream:   false,
+		Options: map[string]any{
+			"temperature": request.Temperature,
+			"seed":        request.Seed,
+		},
+	}
+
+	if request.MaxTokens > 0 {
+		chatRequest.Options["num_predict"] = request.MaxTokens
+	}
+
+	if request.ResponseSchema != nil {
+		chatRequest.Format = request.ResponseSchema.Schema
+	}
+
+	body, err := json.Marshal(chatRequest)
+
+	if err != nil {
+		return ChatResponse{}, err
+	}
+
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/api/chat", bytes.NewReader(body))
+
+	if err != nil {
+		return ChatResponse{}, err
+	}
+
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	httpResponse, err := p.httpClient.Do(httpRequest)
+
+	if err != nil {
+		return ChatResponse{}, &ProviderError{Provider: "OllamaProvider", Err: err}
+	}
+
+	defer httpResponse.Body.Close()
+
+	responseBody, err := io.ReadAll(httpResponse.Body)
+
+	if err != nil {
+		return ChatResponse{}, &ProviderError{Provider: "OllamaProvider", StatusCode: httpResponse.StatusCode, Err: err}
+	}
+
+	var response ollamaChatResponse
+
+	err = json.Unmarshal(responseBody, &response)
+
+	if httpResponse.StatusCode != http.StatusOK {
+		return ChatResponse{}, &ProviderError{Provider: "OllamaProvider", StatusCode: httpResponse.StatusCode, Message: response.Error, RetryAfter: parseRetryAfter(httpResponse.Header)}
+	}
+
+	if err != nil {
+		return ChatResponse{}, &ParseError{Raw: string(responseBody), Err: err}
+	}
+
+	return ChatResponse{Content: response.Message.Content, StopReason: response.DoneReason}, nil
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOllamaProviderChat(t *testing.T) {
+	t.Run("Sends messages to /api/chat and returns the message content. ", func(t *testing.T) {
+		var received ollamaChatRequest
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/chat" {
+				t.Errorf("Expected /api/chat, got %v", r.URL.Path)
+			}
+
+			json.NewDecoder(r.Body).Decode(&received)
+
+			w.Write([]byte(`{"model": "llama3.1", "message": {"role": "assistant", "content": "{\"predicted_class\": \"cat\", \"probability\": 0.9}"}, "done": true, "done_reason": "stop"}`))
+		}))
+		defer server.Close()
+
+		provider := NewOllamaProvider(OllamaProviderOptions{BaseURL: server.URL, Model: "llama3.1"})
+
+		response, err := provider.Chat(context.Background(), ChatRequest{
+			Messages: []Message{
+				{Role: RoleSystem, Content: "You are a classifier."},
+				{Role: RoleUser, Content: "Meow"},
+			},
+			Temperature: 0.3,
+			Seed:        1,
+		})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if response.Content != `{"predicted_class": "cat", "probability": 0.9}` {
+			t.Errorf("Expected message content, got %v", response.Content)
+		}
+
+		if received.Model != "llama3.1" || received.Stream {
+			t.Errorf("Expected non-streaming request for llama3.1, got %+v", received)
+		}
+
+		if len(received.Messages) != 2 || received.Messages[0].Role != RoleSystem {
+			t.Errorf("Expected system and user messages, got %+v", received.Messages)
+		}
+
+		if received.Options["temperature"] != 0.3 {
+			t.Errorf("Expected temperature 0.3, got %v", received.Options["temperature"])
+		}
+	})
+
+	t.Run("Returns an error when the server responds with an error. ", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "model \"missing\" not found, try pulling it first"}`))
+		}))
+		defer server.Close()
+
+		provider := NewOllamaProvider(OllamaProviderOptions{BaseURL: server.URL, Model: "missing"})
+
+		_, err := provider.Chat(context.Background(), ChatRequest{Messages: []Message{{Role: RoleUser, Content: "Hi"}}})
+
+		if err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+	})
+
+	t.Run("Sends the response schema as the format. ", func(t *testing.T) {
+		var received map[string]any
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&received)
+
+			w.Write([]byte(`{"message": {"role": "assistant", "content": "{\"predicted_class\": \"positive\"}"}, "done": true, "done_reason": "stop"}`))
+		}))
+		defer server.Close()
+
+		provider := NewOllamaProvider(OllamaProviderOptions{BaseURL: server.URL})
+
+		_, err := provider.Chat(context.Background(), ChatRequest{
+			Messages: []Message{{Role: RoleUser, Content: "Great game last night!"}},
+			ResponseSchema: &ResponseSchema{
+				Name:   "classification",
+				Schema: map[string]interface{}{"type": "object", "properties": map[string]interface{}{"predicted_class": map[string]interface{}{"type": "string", "enum": []string{"negative", "positive"}}}},
+				Strict: true,
+			},
+		})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		format, _ := received["format"].(map[string]any)
+
+		if format["type"] != "object" {
+			t.Errorf("Expected the schema as format, got %v", received["format"])
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+)
+
+type OpenAIProvider struct {
+	client *openai.Client
+	model  string
+}
+
+type OpenAIProviderOptions struct {
+	APIKey     string       // defaults to OPENAI_API_KEY
+	Model      string       // defaults to gpt-4o-mini
+	BaseURL    string       // for OpenAI-compatible servers, defaults to the OpenAI API
+	HTTPClient *http.Client // defaults to http.DefaultClient
+}
+
+func NewOpenAIProvider(opts ...OpenAIProviderOptions) (*OpenAIProvider, error) {
+	options := OpenAIProviderOptions{}
+
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if options.APIKey == "" {
+		options.APIKey = os.Getenv("OPENAI_API_KEY")
+	}
+
+	if options.APIKey == "" {
+		return nil, &ConfigError{Field: "OPENAI_API_KEY", Err: ErrMissingAPIKey}
+	}
+
+	if options.Model == "" {
+		options.Model = openai.ChatModelGPT4oMini
+	}
+
+	// retries are handled by AI's RetryPolicy so they share its backoff and rate limiter
+	requestOptions := []option.RequestOption{option.WithAPIKey(options.APIKey), option.WithMaxRetries(0)}
+
+	if options.BaseURL != "" {
+		if !strings.HasSuffix(options.BaseURL, "/") {
+			// the client resolves endpoint paths relative to the base URL
+			options.BaseURL += "/"
+		}
+
+		requestOptions = append(requestOptions, option.WithBaseURL(options.BaseURL))
+	}
+
+	if options.HTTPClient != nil {
+		requestOptions = append(requestOptions, option.WithHTTPClient(options.HTTPClient))
+	}
+
+	client := openai.NewClient(requestOptions...)
+
+	return &OpenAIProvider{
+		client: client,
+		model:  options.Model,
+	}, nil
+}
+
+func (p *OpenAIProvider) Chat(ctx context.Context, request ChatRequest) (ChatResponse, error) {
+	messages := []openai.ChatCompletionMessageParamUnion{}
+
+	for _, message := range request.Messages {
+		switch message.Role {
+		case RoleSystem:
+			messages = append(messages, openai.SystemMessage(message.Content))
+		case RoleAssistant:
+			messages = append(messages, openai.AssistantMessage(message.Content))
+		default:
+			messages = append(messages, openai.UserMessage(message.Content))
+		}
+	}
+
+	params := openai.ChatCompletionNewParams{
+		Messages:    openai.F(messages),
+		Seed:        openai.Int(request.Seed),
+		Model:       openai.F(p.model),
+		Temperature: openai.Float(request.Temperature),
+	}
+
+	if request.MaxTokens > 0 {
+		params.MaxTokens = openai.Int(int64(request.MaxTokens))
+	}
+
+	if request.TopLogprobs > 0 {
+		params.Logprobs = openai.Bool(true)
+		params.TopLogprobs = openai.Int(int64(request.TopLogprobs))
+	}
+
+	if request.ResponseSchema != nil {
+		params.ResponseFormat = openai.F[openai.ChatCompletionNewParamsResponseFormatUnion](openai.ResponseFormatJSONSchemaParam{
+			Type: openai.F(openai.ResponseFormatJSONSchemaTypeJSONSchema),
+			JSONSchema: openai.F(openai.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   openai.F(request.ResponseSchema.Name),
+				Schema: openai.F[interface{}](request.ResponseSchema.Schema),
+				Strict: openai.F(request.ResponseSchema.Strict),
+			}),
+		})
+	}
+
+	completions, err := p.client.Chat.Completions.New(ctx, params)
+
+	if err != nil {
+		var apiErr *openai.Error
+
+		if errors.As(err, &apiErr) {
+			providerErr := &ProviderError{Provider: "OpenAIProvider", StatusCode: apiErr.StatusCode, Message: apiErr.Message, Err: err}
+
+			if apiErr.Response != nil {
+				providerErr.RetryAfter = parseRetryAfter(apiErr.Response.Header)
+			}
+
+			return ChatResponse{}, providerErr
+		}
+
+		return ChatResponse{}, &ProviderError{Provider: "OpenAIProvider", Err: err}
+	}
+
+	if len(completions.Choices) == 0 {
+		return ChatResponse{}, &ProviderError{Provider: "OpenAIProvider", Err: ErrEmptyResponse}
+	}
+
+	choice := completions.Choices[0]
+	response := ChatResponse{Content: choice.Message.Content, StopReason: string(choice.FinishReason)}
+
+	for _, tokenLogprob := range choice.Logprobs.Content {
+		topLogprobs := []TopLogprob{}
+
+		for _, top := range tokenLogprob.TopLogprobs {
+			topLogprobs = append(topLogprobs, TopLogprob{Token: top.Token, Logprob: top.Logprob})
+		}
+
+		response.Logprobs = append(response.Logprobs, TokenLogprob{Token: tokenLogprob.Token, Logprob: tokenLogprob.Logprob, TopLogprobs: topLogprobs})
+	}
+
+	return response, nil
+}
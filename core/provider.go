@@ -0,0 +1,78 @@
+package core
+
+import "context"
+
+type MessageRole = string
+
+const (
+	RoleSystem    MessageRole = "system"
+	RoleUser      MessageRole = "user"
+	RoleAssistant MessageRole = "assistant"
+)
+
+type Message struct {
+	Role    MessageRole
+	Content string
+}
+
+type ChatRequest struct {
+	Messages       []Message
+	Temperature    float64
+	Seed           int64
+	ResponseSchema *ResponseSchema // asks for JSON matching the schema where the provider supports it, nil for free-form text
+	MaxTokens      int             // caps the response length, 0 for the provider default
+	TopLogprobs    int             // when > 0, asks for the log probabilities of the most likely tokens at each position
+}
+
+// ResponseSchema describes the JSON object a request should produce.
+type ResponseSchema struct {
+	Name   string                 // letters, digits, underscores and dashes
+	Schema map[string]interface{} // a JSON Schema with an object at the root
+	Strict bool                   // require exact adherence, every object must list all properties as required and disallow others
+}
+
+type ChatResponse struct {
+	Content    string
+	StopReason string         // provider-specific reason the generation ended, e.g. "stop", "end_turn", "max_tokens"
+	Logprobs   []TokenLogprob // one entry per generated token when ChatRequest.TopLogprobs is set and the provider supports it
+}
+
+// TokenLogprob is a generated token with its log probability and the most likely alternatives at its position.
+type TokenLogprob struct {
+	Token       string
+	Logprob     float64
+	TopLogprobs []TopLogprob
+}
+
+type TopLogprob struct {
+	Token   string
+	Logprob float64
+}
+
+// LastUserMessage returns the content of the last user message in the request.
+func (r ChatRequest) LastUserMessage() string {
+	for i := len(r.Messages) - 1; i >= 0; i-- {
+		if r.Messages[i].Role == RoleUser {
+			return r.Messages[i].Content
+		}
+	}
+
+	return ""
+}
+
+// SystemPrompt returns the content of the first system message in the request.
+func (r ChatRequest) SystemPrompt() string {
+	for _, message := range r.Messages {
+		if message.Role == RoleSystem {
+			return message.Content
+		}
+	}
+
+	return ""
+}
+
+// Provider is a chat completion backend (OpenAI, a local model, a fake for tests, etc.)
+// that AI delegates to.
+type Provider interface {
+	Chat(ctx context.Context, request ChatRequest) (ChatResponse, error)
+}
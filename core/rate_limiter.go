@@ -0,0 +1,131 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit caps the request and token throughput sent to a provider. Zero values mean unlimited.
+type RateLimit struct {
+	RequestsPerMinute int
+	TokensPerMinute   int
+}
+
+// RateLimiter is a client-side token-bucket limiter for requests/minute and tokens/minute.
+// It is safe for concurrent use.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests *tokenBucket
+	tokens   *tokenBucket
+}
+
+type tokenBucket struct {
+	capacity  float64
+	available float64
+	perSecond float64
+	updatedAt time.Time
+}
+
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	now := time.Now()
+
+	return &RateLimiter{
+		requests: newTokenBucket(limit.RequestsPerMinute, now),
+		tokens:   newTokenBucket(limit.TokensPerMinute, now),
+	}
+}
+
+func newTokenBucket(perMinute int, now time.Time) *tokenBucket {
+	if perMinute <= 0 {
+		return nil
+	}
+
+	return &tokenBucket{
+		capacity:  float64(perMinute),
+		available: float64(perMinute),
+		perSecond: float64(perMinute) / 60,
+		updatedAt: now,
+	}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	elapsed := now.Sub(b.updatedAt).Seconds()
+
+	if elapsed > 0 {
+		b.available = min(b.capacity, b.available+elapsed*b.perSecond)
+		b.updatedAt = now
+	}
+}
+
+// wait returns how long until amount can be taken from the bucket.
+func (b *tokenBucket) wait(amount float64) time.Duration {
+	if b == nil || b.available >= amount {
+		return 0
+	}
+
+	return time.Duration((amount - b.available) / b.perSecond * float64(time.Second))
+}
+
+// Wait blocks until one request carrying the estimated number of tokens fits into both budgets,
+// then reserves it. It returns ctx.Err() if ctx is done first.
+func (l *RateLimiter) Wait(ctx context.Context, tokens int) error {
+	for {
+		l.mu.Lock()
+
+		now := time.Now()
+		tokenAmount := float64(tokens)
+
+		if l.requests != nil {
+			l.requests.refill(now)
+		}
+
+		if l.tokens != nil {
+			l.tokens.refill(now)
+			// a single request larger than the whole budget would otherwise wait forever
+			tokenAmount = min(tokenAmount, l.tokens.capacity)
+		}
+
+		delay := max(l.requests.wait(1), l.tokens.wait(tokenAmount))
+
+		if delay == 0 {
+			if l.requests != nil {
+				l.requests.available--
+			}
+
+			if l.tokens != nil {
+				l.tokens.available -= tokenAmount
+			}
+
+			l.mu.Unlock()
+			return nil
+		}
+
+		l.mu.Unlock()
+
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// defaultResponseTokens is reserved for the response of a request without MaxTokens.
+const defaultResponseTokens = 256
+
+// estimateTokens approximates the tokens a request counts against a tokens-per-minute limit: its prompt
+// (~4 characters per token) plus its response, at most request.MaxTokens.
+func estimateTokens(request ChatRequest) int {
+	characters := 0
+
+	for _, message := range request.Messages {
+		characters += len(message.Content)
+	}
+
+	responseTokens := request.MaxTokens
+
+	if responseTokens <= 0 {
+		responseTokens = defaultResponseTokens
+	}
+
+	return characters/4 + 1 + responseTokens
+}
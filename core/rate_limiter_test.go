@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Run("Allows bursts up to the per-minute budget. ", func(t *testing.T) {
+		limiter := NewRateLimiter(RateLimit{RequestsPerMinute: 3})
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		for range 3 {
+			if err := limiter.Wait(ctx, 1); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}
+	})
+
+	t.Run("Blocks once the request budget is used up. ", func(t *testing.T) {
+		limiter := NewRateLimiter(RateLimit{RequestsPerMinute: 1})
+
+		limiter.Wait(context.Background(), 1)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := limiter.Wait(ctx, 1)
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("Blocks once the token budget is used up. ", func(t *testing.T) {
+		limiter := NewRateLimiter(RateLimit{TokensPerMinute: 1000})
+
+		limiter.Wait(context.Background(), 900)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := limiter.Wait(ctx, 200)
+
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Expected context.DeadlineExceeded, got %v", err)
+		}
+	})
+
+	t.Run("Refills the bucket over time. ", func(t *testing.T) {
+		limiter := NewRateLimiter(RateLimit{RequestsPerMinute: 1200}) // one request every 50ms
+
+		for range 1200 {
+			limiter.Wait(context.Background(), 1)
+		}
+
+		start := time.Now()
+		err := limiter.Wait(context.Background(), 1)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the bucket to refill within a second, waited %v", elapsed)
+		}
+	})
+}
+
+func TestEstimateTokens(t *testing.T) {
+	t.Run("Counts the prompt and the response. ", func(t *testing.T) {
+		request := ChatRequest{Messages: []Message{{Role: RoleUser, Content: strings.Repeat("a", 400)}}}
+
+		if tokens := estimateTokens(request); tokens != 101+defaultResponseTokens {
+			t.Errorf("Expected %d tokens without MaxTokens, got %v", 101+defaultResponseTokens, tokens)
+		}
+
+		request.MaxTokens = 50
+
+		if tokens := estimateTokens(request); tokens != 151 {
+			t.Errorf("Expected 151 tokens with MaxTokens, got %v", tokens)
+		}
+	})
+}
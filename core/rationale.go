@@ -0,0 +1,174 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// PredictOptions are per-call options of the PredictOne* methods.
+type PredictOptions struct {
+	IncludeRationale bool // explain the prediction, off by default to save tokens
+}
+
+// InfluentialDescription is a label description from the trained prompts that drove a prediction.
+type InfluentialDescription struct {
+	Label       Label            `json:"label"`
+	Description LabelDescription `json:"description"`
+}
+
+type predictionExplanation struct {
+	Rationale               string `json:"rationale"`
+	InfluentialDescriptions []int  `json:"influential_descriptions"`
+}
+
+func (predictionExplanation) requiredJsonKeys() []string {
+	return []string{"rationale"}
+}
+
+// explainedClassification is a classification response that also explains the prediction.
+type explainedClassification struct {
+	ClassificationResult
+	InfluentialDescriptions []int `json:"influential_descriptions"`
+}
+
+// requiredJsonKeys leaves out the rationale, a prediction without one is explained in one more call.
+func (explainedClassification) requiredJsonKeys() []string {
+	return []string{"predicted_class", "probability"}
+}
+
+// maximum number of influential descriptions kept per prediction
+const maxInfluentialDescriptions = 3
+
+// numberedClassDescriptors lists every label description with an ID so the model can cite them.
+func (c *TaoClassifier) numberedClassDescriptors() (string, []InfluentialDescription) {
+	labels, _ := c.GetAvailableLabels()
+
+	classDescriptors := ""
+	descriptions := []InfluentialDescription{}
+
+	for _, label := range labels {
+		for _, description := range c.prompts[label] {
+			descriptions = append(descriptions, InfluentialDescription{Label: label, Description: description})
+			classDescriptors += fmt.Sprintf("[%d] %s: %s\n", len(descriptions), label, description)
+		}
+	}
+
+	return classDescriptors, descriptions
+}
+
+// explanationInstructions extends a classification prompt to explain the prediction in the same response.
+// The descriptions are numbered so the model can cite them.
+func (c *TaoClassifier) explanationInstructions() (string, []InfluentialDescription) {
+	classDescriptors, descriptions := c.numberedClassDescriptors()
+
+	return fmt.Sprintf(`Also explain briefly why the data point was assigned its class in "rationale" (one or two sentences), and list the IDs of the numbered descriptions below that most influenced the decision in "influential_descriptions", most influential first (at most %d).
+	Descriptions:
+%s`, maxInfluentialDescriptions, classDescriptors), descriptions
+}
+
+// explainOrKeep adds an explanation to result with explainPrediction. When only the explanation fails the
+// prediction is returned without a rationale, so a prediction is never lost to its explanation.
+func (c *TaoClassifier) explainOrKeep(ctx context.Context, text string, result ClassificationResult) ClassificationResult {
+	explained, err := c.explainPrediction(ctx, text, result)
+
+	if err != nil {
+		if c.verbose {
+			fmt.Println("explainPrediction: keeping the prediction without a rationale:", err)
+		}
+
+		return result
+	}
+
+	return explained
+}
+
+// explainPrediction asks the model why text was assigned result's class and which descriptions mattered most.
+func (c *TaoClassifier) explainPrediction(ctx context.Context, text string, result ClassificationResult) (ClassificationResult, error) {
+	classDescriptors, descriptions := c.numberedClassDescriptors()
+
+	systemPrompt := fmt.Sprintf(`You are an AI assistant that explains classification decisions.
+	You will be given numbered class descriptions, a data point and the class it was assigned.
+	Explain briefly why the data point was assigned the class and list the IDs of the descriptions that most influenced the decision, most influential first (at most %d).
+	Respond in JSON with { "rationale": <one or two sentences>, "influential_descriptions": [<id>] }.
+	Descriptions:
+%s`, maxInfluentialDescriptions, classDescriptors)
+
+	userPrompt := fmt.Sprintf("Text: %q\nAssigned class: %v", text, result.PredictedClass)
+
+	response, err := c.ai.GenerateTextContext(ctx, userPrompt, GenerateTextOptions{Verbose: false, System: systemPrompt, ResponseSchema: explanationSchema()})
+
+	if err != nil {
+		return result, fmt.Errorf("explainPrediction: failed to generate completions: %w", err)
+	}
+
+	if c.verbose {
+		fmt.Println("Prompt: ", userPrompt)
+		fmt.Println("Generated Text: ", response)
+	}
+
+	explanation, err := CleanGPTJson[predictionExplanation](response)
+
+	if err != nil {
+		return result, fmt.Errorf("explainPrediction: failed to parse explanation: %w", err)
+	}
+
+	result.Rationale = explanation.Rationale
+	result.InfluentialDescriptions = citedDescriptions(explanation.InfluentialDescriptions, descriptions)
+
+	return result, nil
+}
+
+// citedDescriptions returns the descriptions of the cited IDs, at most maxInfluentialDescriptions.
+func citedDescriptions(ids []int, descriptions []InfluentialDescription) []InfluentialDescription {
+	influential := []InfluentialDescription{}
+	cited := map[int]bool{}
+
+	for _, id := range ids {
+		// IDs start at 1, made up or repeated IDs are skipped
+		if id < 1 || id > len(descriptions) || cited[id] {
+			continue
+		}
+
+		cited[id] = true
+		influential = append(influential, descriptions[id-1])
+
+		if len(influential) == maxInfluentialDescriptions {
+			break
+		}
+	}
+
+	return influential
+}
+
+// withExplanation adds the rationale and the cited description IDs to a classification schema.
+func withExplanation(schema *ResponseSchema) *ResponseSchema {
+	properties := schema.Schema["properties"].(map[string]interface{})
+	properties["rationale"] = map[string]interface{}{"type": "string"}
+	properties["influential_descriptions"] = map[string]interface{}{
+		"type":  "array",
+		"items": map[string]interface{}{"type": "integer"},
+	}
+
+	schema.Schema["required"] = append(schema.Schema["required"].([]string), "rationale", "influential_descriptions")
+
+	return schema
+}
+
+func explanationSchema() *ResponseSchema {
+	return &ResponseSchema{
+		Name: "prediction_explanation",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"rationale": map[string]interface{}{"type": "string"},
+				"influential_descriptions": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"type": "integer"},
+				},
+			},
+			"required":             []string{"rationale", "influential_descriptions"},
+			"additionalProperties": false,
+		},
+		Strict: true,
+	}
+}
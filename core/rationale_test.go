@@ -0,0 +1,101 @@
+package core
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRationale(t *testing.T) {
+	prompts := map[Label][]LabelDescription{
+		"cat": {"meows", "purrs when happy"},
+		"dog": {"barks"},
+	}
+
+	t.Run("Doesn't explain predictions by default. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"predicted_class": "cat", "probability": 0.9}`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Purr")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.Rationale != "" || len(provider.Requests()) != 1 {
+			t.Errorf("Expected a single call without rationale, got %v calls and %q", len(provider.Requests()), result.Rationale)
+		}
+	})
+
+	t.Run("Returns the rationale and the cited descriptions from the classification request. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"predicted_class": "cat", "probability": 0.9, "rationale": "Purring is typical for cats.", "influential_descriptions": [2, 2, 42, 1]}`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOneRowItem(RowItem{"sound": "Purr"}, PredictOptions{IncludeRationale: true})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.PredictedClass != "cat" || result.Rationale != "Purring is typical for cats." {
+			t.Errorf("Expected cat with the rationale, got %v and %q", result.PredictedClass, result.Rationale)
+		}
+
+		// descriptions are numbered in label order: [1] cat: meows, [2] cat: purrs when happy, [3] dog: barks
+		expected := []InfluentialDescription{{Label: "cat", Description: "purrs when happy"}, {Label: "cat", Description: "meows"}}
+
+		if len(result.InfluentialDescriptions) != 2 || result.InfluentialDescriptions[0] != expected[0] || result.InfluentialDescriptions[1] != expected[1] {
+			t.Errorf("Expected %v, got %v", expected, result.InfluentialDescriptions)
+		}
+
+		requests := provider.Requests()
+
+		if len(requests) != 1 || !strings.Contains(requests[0].SystemPrompt(), "[3] dog: barks") {
+			t.Fatalf("Expected a single request with numbered descriptions, got %v requests", len(requests))
+		}
+
+		if _, ok := requests[0].ResponseSchema.Schema["properties"].(map[string]interface{})["rationale"]; !ok {
+			t.Errorf("Expected the rationale in the response schema, got %v", requests[0].ResponseSchema.Schema)
+		}
+	})
+
+	t.Run("Explains a prediction without a rationale in one more call. ", func(t *testing.T) {
+		provider := NewFakeProvider(
+			`{"predicted_class": "cat", "probability": 0.9}`,
+			`{"rationale": "Purring is typical for cats.", "influential_descriptions": [2]}`,
+		)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Purr", PredictOptions{IncludeRationale: true})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.Rationale != "Purring is typical for cats." || len(result.InfluentialDescriptions) != 1 {
+			t.Errorf("Expected the rationale and one description, got %q and %v", result.Rationale, result.InfluentialDescriptions)
+		}
+
+		if explanationRequest := provider.Requests()[1]; !strings.Contains(explanationRequest.LastUserMessage(), "Assigned class: cat") {
+			t.Errorf("Expected the assigned class, got %+v", explanationRequest.Messages)
+		}
+	})
+
+	t.Run("Keeps the prediction when only the explanation fails. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"predicted_class": "cat", "probability": 0.9}`, `not json`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Purr", PredictOptions{IncludeRationale: true})
+
+		if err != nil || result.PredictedClass != "cat" || result.Rationale != "" {
+			t.Errorf("Expected cat without a rationale, got %v, %q and %v", result.PredictedClass, result.Rationale, err)
+		}
+	})
+}
@@ -0,0 +1,253 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+)
+
+type CassetteMode int
+
+const (
+	// CassetteReplay serves responses from the cassette and never touches the network.
+	CassetteReplay CassetteMode = iota
+	// CassetteRecord forwards requests to the real transport and stores the interactions.
+	CassetteRecord
+)
+
+type Cassette struct {
+	Interactions []CassetteInteraction `json:"interactions"`
+}
+
+type CassetteInteraction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// CassetteRequest deliberately leaves out the request headers so API keys never end up in testdata.
+type CassetteRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Body   string `json:"body"`
+}
+
+type CassetteResponse struct {
+	StatusCode int               `json:"status_code"`
+	Headers    map[string]string `json:"headers,omitempty"`
+	Body       string            `json:"body"`
+}
+
+// RecordReplayTransport is an http.RoundTripper that records request/response pairs into a cassette file
+// and replays them later, so provider calls can be tested offline and reproducibly.
+type RecordReplayTransport struct {
+	mu       sync.Mutex
+	path     string
+	mode     CassetteMode
+	cassette Cassette
+	used     []bool
+	next     http.RoundTripper
+}
+
+func NewRecordReplayTransport(cassettePath string, mode CassetteMode) (*RecordReplayTransport, error) {
+	transport := &RecordReplayTransport{
+		path: cassettePath,
+		mode: mode,
+		next: http.DefaultTransport,
+	}
+
+	if mode == CassetteRecord {
+		return transport, nil
+	}
+
+	cassetteBytes, err := os.ReadFile(cassettePath)
+
+	if err != nil {
+		return nil, fmt.Errorf("NewRecordReplayTransport: failed to read cassette: %v", err)
+	}
+
+	err = json.Unmarshal(cassetteBytes, &transport.cassette)
+
+	if err != nil {
+		return nil, fmt.Errorf("NewRecordReplayTransport: failed to parse cassette: %v", err)
+	}
+
+	transport.used = make([]bool, len(transport.cassette.Interactions))
+
+	return transport, nil
+}
+
+// Client returns an http.Client using this transport, to be passed to a provider.
+func (t *RecordReplayTransport) Client() *http.Client {
+	return &http.Client{Transport: t}
+}
+
+func (t *RecordReplayTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	body := ""
+
+	if request.Body != nil {
+		bodyBytes, err := io.ReadAll(request.Body)
+
+		if err != nil {
+			return nil, err
+		}
+
+		request.Body.Close()
+		request.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+		body = string(bodyBytes)
+	}
+
+	cassetteRequest := CassetteRequest{
+		Method: request.Method,
+		URL:    request.URL.String(),
+		Body:   body,
+	}
+
+	if t.mode == CassetteRecord {
+		return t.record(request, cassetteRequest)
+	}
+
+	return t.replay(request, cassetteRequest)
+}
+
+func (t *RecordReplayTransport) record(request *http.Request, cassetteRequest CassetteRequest) (*http.Response, error) {
+	response, err := t.next.RoundTrip(request)
+
+	if err != nil {
+		return nil, err
+	}
+
+	responseBytes, err := io.ReadAll(response.Body)
+	response.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = io.NopCloser(bytes.NewReader(responseBytes))
+
+	headers := map[string]string{}
+
+	for _, key := range []string{"Content-Type", "Retry-After"} {
+		if value := response.Header.Get(key); value != "" {
+			headers[key] = value
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.cassette.Interactions = append(t.cassette.Interactions, CassetteInteraction{
+		Request: cassetteRequest,
+		Response: CassetteResponse{
+			StatusCode: response.StatusCode,
+			Headers:    headers,
+			Body:       string(responseBytes),
+		},
+	})
+
+	return response, nil
+}
+
+// replay returns the first unused interaction with the same method, URL and body. JSON bodies are compared
+// after decoding, so only key order and whitespace may differ. A request that matches no interaction is an
+// error: the code under test sends something other than what was recorded and the cassette must be re-recorded.
+func (t *RecordReplayTransport) replay(request *http.Request, cassetteRequest CassetteRequest) (*http.Response, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	match := -1
+	sameURL := false
+
+	for index, interaction := range t.cassette.Interactions {
+		if t.used[index] || interaction.Request.Method != cassetteRequest.Method || interaction.Request.URL != cassetteRequest.URL {
+			continue
+		}
+
+		sameURL = true
+
+		if sameBody(interaction.Request.Body, cassetteRequest.Body) {
+			match = index
+			break
+		}
+	}
+
+	if match == -1 && sameURL {
+		return nil, fmt.Errorf("RecordReplayTransport: request body for %s %s matches no recorded interaction in %s, re-record the cassette: %s", cassetteRequest.Method, cassetteRequest.URL, t.path, cassetteRequest.Body)
+	}
+
+	if match == -1 {
+		return nil, fmt.Errorf("RecordReplayTransport: no recorded interaction left for %s %s in %s", cassetteRequest.Method, cassetteRequest.URL, t.path)
+	}
+
+	t.used[match] = true
+	recorded := t.cassette.Interactions[match].Response
+
+	header := http.Header{}
+
+	for key, value := range recorded.Headers {
+		header.Set(key, value)
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(recorded.Body))),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       request,
+	}, nil
+}
+
+// sameBody reports whether two request bodies are equal, comparing JSON bodies by value.
+func sameBody(recorded string, body string) bool {
+	if recorded == body {
+		return true
+	}
+
+	var recordedValue, value interface{}
+
+	if json.Unmarshal([]byte(recorded), &recordedValue) != nil || json.Unmarshal([]byte(body), &value) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(recordedValue, value)
+}
+
+// Save writes the recorded interactions to the cassette file. It is a no-op in replay mode.
+func (t *RecordReplayTransport) Save() error {
+	if t.mode != CassetteRecord {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	err := CreateFolderIfNotExists(filepath.Dir(t.path))
+
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false) // keep prompts readable in diffs
+	encoder.SetIndent("", "  ")
+
+	err = encoder.Encode(t.cassette)
+
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(t.path, buffer.Bytes(), 0644)
+}
@@ -0,0 +1,112 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordReplayTransport(t *testing.T) {
+	t.Run("Replays a recorded interaction without the server. ", func(t *testing.T) {
+		calls := 0
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Write([]byte(`{"message": {"role": "assistant", "content": "recorded answer"}, "done": true}`))
+		}))
+
+		cassettePath := filepath.Join(t.TempDir(), "cassettes", "ollama.json")
+		request := ChatRequest{Messages: []Message{{Role: RoleUser, Content: "Hi"}}}
+
+		recorder, err := NewRecordReplayTransport(cassettePath, CassetteRecord)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		provider := NewOllamaProvider(OllamaProviderOptions{BaseURL: server.URL, HTTPClient: recorder.Client()})
+
+		_, err = provider.Chat(context.Background(), request)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		err = recorder.Save()
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		server.Close()
+
+		player, err := NewRecordReplayTransport(cassettePath, CassetteReplay)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		provider = NewOllamaProvider(OllamaProviderOptions{BaseURL: server.URL, HTTPClient: player.Client()})
+
+		response, err := provider.Chat(context.Background(), request)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if response.Content != "recorded answer" {
+			t.Errorf("Expected recorded answer, got %v", response.Content)
+		}
+
+		if calls != 1 {
+			t.Errorf("Expected the server to be called once, got %v", calls)
+		}
+
+		_, err = provider.Chat(context.Background(), request)
+
+		if err == nil {
+			t.Errorf("Expected an error once the cassette is used up, got nil")
+		}
+	})
+
+	t.Run("Fails when the request body differs from the recording. ", func(t *testing.T) {
+		cassettePath := filepath.Join(t.TempDir(), "cassette.json")
+		cassette := `{"interactions": [{"request": {"method": "POST", "url": "http://localhost/api", "body": "{\"a\": 1, \"b\": \"x\"}"}, "response": {"status_code": 200, "body": "ok"}}]}`
+
+		if err := os.WriteFile(cassettePath, []byte(cassette), 0644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		player, err := NewRecordReplayTransport(cassettePath, CassetteReplay)
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		_, err = player.Client().Post("http://localhost/api", "application/json", strings.NewReader(`{"b":"y","a":1}`))
+
+		if err == nil || !strings.Contains(err.Error(), "re-record the cassette") {
+			t.Errorf("Expected a body mismatch error, got %v", err)
+		}
+
+		response, err := player.Client().Post("http://localhost/api", "application/json", strings.NewReader(`{"b":"x","a":1}`))
+
+		if err != nil {
+			t.Fatalf("Expected the same JSON in another key order to match, got %v", err)
+		}
+
+		response.Body.Close()
+	})
+
+	t.Run("Fails for a missing cassette in replay mode. ", func(t *testing.T) {
+		_, err := NewRecordReplayTransport(filepath.Join(t.TempDir(), "missing.json"), CassetteReplay)
+
+		if err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy controls how failed provider calls are retried. Rate limits (429), timeouts and
+// server errors (5xx) are retried with exponential backoff; other errors are returned immediately.
+type RetryPolicy struct {
+	MaxAttempts    int           // total attempts including the first one, 1 disables retries
+	InitialBackoff time.Duration // delay before the first retry
+	MaxBackoff     time.Duration // upper bound for a single delay, a longer Retry-After fails the call instead
+	Multiplier     float64       // growth factor between consecutive delays
+	Jitter         float64       // fraction of each delay that is randomized, between 0 and 1
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     30 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// Backoff returns the delay before retrying after the given (1-based) failed attempt.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	delay := float64(p.InitialBackoff) * math.Pow(p.Multiplier, float64(attempt-1))
+
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	if p.Jitter > 0 {
+		// spread retries of concurrent callers: delay * [1 - jitter, 1 + jitter)
+		delay = delay * (1 - p.Jitter + 2*p.Jitter*rand.Float64())
+	}
+
+	return time.Duration(delay)
+}
+
+// delay returns the wait before the next attempt, honoring the provider's Retry-After if it's longer.
+// It returns false when Retry-After is longer than MaxBackoff: retrying earlier would be rejected again.
+func (p RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	delay := p.Backoff(attempt)
+
+	var providerErr *ProviderError
+
+	if errors.As(err, &providerErr) && providerErr.RetryAfter > delay {
+		if p.MaxBackoff > 0 && providerErr.RetryAfter > p.MaxBackoff {
+			return 0, false
+		}
+
+		delay = providerErr.RetryAfter
+	}
+
+	return delay, true
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	defaults := DefaultRetryPolicy()
+
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = defaults.MaxAttempts
+	}
+
+	if p.Multiplier <= 0 {
+		p.Multiplier = defaults.Multiplier
+	}
+
+	if p.Jitter < 0 || p.Jitter > 1 {
+		p.Jitter = defaults.Jitter
+	}
+
+	return p
+}
+
+// IsRetryable reports whether err is a transient provider failure worth retrying.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var providerErr *ProviderError
+
+	if !errors.As(err, &providerErr) {
+		return false
+	}
+
+	switch {
+	case providerErr.StatusCode == 0:
+		// no response at all, e.g. connection reset
+		return true
+	case providerErr.StatusCode == http.StatusTooManyRequests,
+		providerErr.StatusCode == http.StatusRequestTimeout,
+		providerErr.StatusCode == http.StatusConflict,
+		providerErr.StatusCode >= 500:
+		return true
+	}
+
+	return false
+}
+
+// parseRetryAfter reads the Retry-After header (seconds or HTTP date) and OpenAI's retry-after-ms.
+func parseRetryAfter(header http.Header) time.Duration {
+	if header == nil {
+		return 0
+	}
+
+	if milliseconds, err := strconv.ParseFloat(header.Get("Retry-After-Ms"), 64); err == nil && milliseconds > 0 {
+		return time.Duration(milliseconds * float64(time.Millisecond))
+	}
+
+	value := header.Get("Retry-After")
+
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("Backoff grows exponentially and is capped. ", func(t *testing.T) {
+		policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond, Multiplier: 2}
+
+		expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond}
+
+		for index, delay := range expected {
+			if backoff := policy.Backoff(index + 1); backoff != delay {
+				t.Errorf("Expected %v for attempt %d, got %v", delay, index+1, backoff)
+			}
+		}
+	})
+
+	t.Run("Jitter keeps the backoff within bounds. ", func(t *testing.T) {
+		policy := RetryPolicy{InitialBackoff: 100 * time.Millisecond, Multiplier: 2, Jitter: 0.5}
+
+		for range 20 {
+			backoff := policy.Backoff(1)
+
+			if backoff < 50*time.Millisecond || backoff >= 150*time.Millisecond {
+				t.Errorf("Expected backoff within [50ms, 150ms), got %v", backoff)
+			}
+		}
+	})
+
+	t.Run("Only transient provider errors are retryable. ", func(t *testing.T) {
+		cases := map[error]bool{
+			&ProviderError{StatusCode: http.StatusTooManyRequests}:                     true,
+			&ProviderError{StatusCode: http.StatusBadGateway}:                          true,
+			&ProviderError{Err: errors.New("connection reset")}:                        true,
+			&ProviderError{StatusCode: http.StatusBadRequest}:                          false,
+			&ProviderError{StatusCode: http.StatusUnauthorized}:                        false,
+			&ProviderError{Err: context.Canceled}:                                      false,
+			&ParseError{Err: errors.New("unexpected end of JSON input")}:               false,
+			&ConfigError{Field: "OPENAI_API_KEY", Err: ErrMissingAPIKey}:               false,
+			&ProviderError{StatusCode: http.StatusServiceUnavailable, RetryAfter: 1e9}: true,
+		}
+
+		for err, expected := range cases {
+			if IsRetryable(err) != expected {
+				t.Errorf("Expected IsRetryable(%v) to be %v", err, expected)
+			}
+		}
+	})
+
+	t.Run("Parses Retry-After in seconds and milliseconds. ", func(t *testing.T) {
+		if delay := parseRetryAfter(http.Header{"Retry-After": {"2"}}); delay != 2*time.Second {
+			t.Errorf("Expected 2s, got %v", delay)
+		}
+
+		if delay := parseRetryAfter(http.Header{"Retry-After-Ms": {"150"}}); delay != 150*time.Millisecond {
+			t.Errorf("Expected 150ms, got %v", delay)
+		}
+
+		if delay := parseRetryAfter(http.Header{}); delay != 0 {
+			t.Errorf("Expected 0, got %v", delay)
+		}
+	})
+}
+
+func TestAIRetries(t *testing.T) {
+	fastPolicy := &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, Multiplier: 2}
+
+	t.Run("Retries rate-limited calls until they succeed. ", func(t *testing.T) {
+		provider := NewScriptedFakeProvider(
+			FakeResponse{Err: &ProviderError{Provider: "FakeProvider", StatusCode: http.StatusTooManyRequests}},
+			FakeResponse{Err: &ProviderError{Provider: "FakeProvider", StatusCode: http.StatusInternalServerError}},
+			FakeResponse{Content: "done"},
+		)
+
+		ai := NewAIWithProvider(provider, AIOptions{RetryPolicy: fastPolicy})
+
+		result, err := ai.GenerateText("Hello")
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if result != "done" || len(provider.Requests()) != 3 {
+			t.Errorf("Expected success on the third attempt, got %v after %d attempts", result, len(provider.Requests()))
+		}
+	})
+
+	t.Run("Does not retry non-transient errors. ", func(t *testing.T) {
+		provider := NewScriptedFakeProvider(
+			FakeResponse{Err: &ProviderError{Provider: "FakeProvider", StatusCode: http.StatusBadRequest}},
+			FakeResponse{Content: "unreachable"},
+		)
+
+		ai := NewAIWithProvider(provider, AIOptions{RetryPolicy: fastPolicy})
+
+		_, err := ai.GenerateText("Hello")
+
+		if err == nil || len(provider.Requests()) != 1 {
+			t.Errorf("Expected a single failed attempt, got %d attempts and error %v", len(provider.Requests()), err)
+		}
+	})
+
+	t.Run("Gives up after MaxAttempts. ", func(t *testing.T) {
+		rateLimited := FakeResponse{Err: &ProviderError{Provider: "FakeProvider", StatusCode: http.StatusTooManyRequests}}
+		provider := NewScriptedFakeProvider(rateLimited, rateLimited, rateLimited, rateLimited)
+
+		ai := NewAIWithProvider(provider, AIOptions{RetryPolicy: fastPolicy})
+
+		_, err := ai.GenerateText("Hello")
+
+		var providerErr *ProviderError
+
+		if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusTooManyRequests {
+			t.Errorf("Expected the last ProviderError, got %v", err)
+		}
+
+		if len(provider.Requests()) != 3 {
+			t.Errorf("Expected 3 attempts, got %v", len(provider.Requests()))
+		}
+	})
+
+	t.Run("Honors Retry-After. ", func(t *testing.T) {
+		provider := NewScriptedFakeProvider(
+			FakeResponse{Err: &ProviderError{Provider: "FakeProvider", StatusCode: http.StatusTooManyRequests, RetryAfter: 50 * time.Millisecond}},
+			FakeResponse{Content: "done"},
+		)
+
+		ai := NewAIWithProvider(provider, AIOptions{RetryPolicy: fastPolicy})
+
+		start := time.Now()
+		_, err := ai.GenerateText("Hello")
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("Expected to wait at least 50ms, waited %v", elapsed)
+		}
+	})
+
+	t.Run("Fails instead of retrying before a Retry-After longer than MaxBackoff. ", func(t *testing.T) {
+		provider := NewScriptedFakeProvider(
+			FakeResponse{Err: &ProviderError{Provider: "FakeProvider", StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}},
+			FakeResponse{Content: "done"},
+		)
+
+		ai := NewAIWithProvider(provider, AIOptions{RetryPolicy: &RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Second}})
+
+		_, err := ai.GenerateText("Hello")
+
+		var providerErr *ProviderError
+
+		if !errors.As(err, &providerErr) || providerErr.RetryAfter != time.Hour {
+			t.Errorf("Expected the ProviderError with its Retry-After, got %v", err)
+		}
+
+		if len(provider.Requests()) != 1 {
+			t.Errorf("Expected a single attempt, got %v", len(provider.Requests()))
+		}
+	})
+}
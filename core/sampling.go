@@ -0,0 +1,266 @@
+package core
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+)
+
+// SamplingStrategy decides in which order Train sends dataset rows to the model.
+type SamplingStrategy string
+
+const (
+	SamplingStratified SamplingStrategy = "stratified" // rows of every class in random order, the classes take turns
+	SamplingShuffle    SamplingStrategy = "shuffle"    // all rows in random order, without replacement
+	SamplingDiversity  SamplingStrategy = "diversity"  // rows of every class that differ most from the ones picked before, the classes take turns
+)
+
+// trainingSample is a dataset row used to profile one of its classes.
+type trainingSample struct {
+	class Label
+	row   int
+}
+
+// samplingPlan orders the rows of groups per c.samplingStrategy. Every row appears once per class it
+// belongs to, classes that aren't in classes are left out.
+func (c *TaoClassifier) samplingPlan(groups map[Label][]int, classes []Label) []trainingSample {
+	orders := map[Label][]int{}
+	var features *rowFeatures
+
+	if c.samplingStrategy == SamplingDiversity {
+		features = c.rowFeatures()
+	}
+
+	for _, class := range classes {
+		rows := append([]int{}, groups[class]...)
+
+		switch c.samplingStrategy {
+		case SamplingDiversity:
+			rows = diversityOrder(rows, features, c.trainingRowBudget())
+		default:
+			rand.Shuffle(len(rows), func(i, j int) { rows[i], rows[j] = rows[j], rows[i] })
+		}
+
+		orders[class] = rows
+	}
+
+	if c.samplingStrategy != SamplingShuffle {
+		return interleave(orders, classes)
+	}
+
+	rowClasses := map[int][]Label{}
+
+	for _, class := range classes {
+		for _, row := range orders[class] {
+			rowClasses[row] = append(rowClasses[row], class)
+		}
+	}
+
+	plan := []trainingSample{}
+
+	for _, row := range rand.Perm(len(c.dataset)) {
+		for _, class := range rowClasses[row] {
+			plan = append(plan, trainingSample{class: class, row: row})
+		}
+	}
+
+	return plan
+}
+
+// trainingBatchRows is the number of rows Train sends in one profile call.
+func (c *TaoClassifier) trainingBatchRows() int {
+	if c.refineProfiles {
+		return refineBatchRows
+	}
+
+	return 1
+}
+
+// trainingRowBudget is the most rows MaxTrainingRows and MaxTrainingCalls let Train use for one class, 0 when
+// neither is set.
+func (c *TaoClassifier) trainingRowBudget() int {
+	budget := c.maxTrainingRows
+
+	if c.maxTrainingCalls > 0 {
+		calls := c.maxTrainingCalls * c.trainingBatchRows()
+
+		if budget == 0 || calls < budget {
+			budget = calls
+		}
+	}
+
+	return budget
+}
+
+// interleave takes the rows of the classes in turns, so a budget is shared fairly between the classes. Few-shot
+// selection uses it the same way with example indexes.
+func interleave(orders map[Label][]int, classes []Label) []trainingSample {
+	plan := []trainingSample{}
+
+	for position := 0; ; position++ {
+		added := false
+
+		for _, class := range classes {
+			if position < len(orders[class]) {
+				plan = append(plan, trainingSample{class: class, row: orders[class][position]})
+				added = true
+			}
+		}
+
+		if !added {
+			return plan
+		}
+	}
+}
+
+// diversityOrder orders rows by farthest-point sampling: starting from a random row, the next row is always
+// the one with the largest feature distance to the closest row picked before. With a limit above 0 only the
+// first limit rows are picked that way, the others follow in random order.
+func diversityOrder(rows []int, features *rowFeatures, limit int) []int {
+	if len(rows) == 0 {
+		return rows
+	}
+
+	if limit <= 0 || limit > len(rows) {
+		limit = len(rows)
+	}
+
+	first := rand.Intn(len(rows))
+	order := []int{rows[first]}
+	remaining := append(append([]int{}, rows[:first]...), rows[first+1:]...)
+	closest := make([]float64, len(remaining))
+
+	for i, row := range remaining {
+		closest[i] = features.distance(row, rows[first])
+	}
+
+	for len(order) < limit {
+		farthest := 0
+
+		for i := range remaining {
+			if closest[i] > closest[farthest] {
+				farthest = i
+			}
+		}
+
+		picked := remaining[farthest]
+		order = append(order, picked)
+
+		remaining = append(remaining[:farthest], remaining[farthest+1:]...)
+		closest = append(closest[:farthest], closest[farthest+1:]...)
+
+		for i, row := range remaining {
+			closest[i] = math.Min(closest[i], features.distance(row, picked))
+		}
+	}
+
+	rand.Shuffle(len(remaining), func(i, j int) { remaining[i], remaining[j] = remaining[j], remaining[i] })
+
+	return append(order, remaining...)
+}
+
+// rowFeatures holds the dataset columns other than the target column, parsed once for diversityOrder. A
+// column is numeric when all of its values are numbers.
+type rowFeatures struct {
+	numbers [][]float64 // per row, the value of every numeric column or NaN when missing
+	texts   [][]string  // per row, the value of every other column
+	ranges  []float64   // max - min of every numeric column
+}
+
+// rowFeatures parses the feature columns of c.dataset.
+func (c *TaoClassifier) rowFeatures() *rowFeatures {
+	textColumns := map[string]bool{}
+	seen := map[string]bool{}
+
+	for _, row := range c.dataset {
+		for column, value := range row {
+			if column == c.targetColumn {
+				continue
+			}
+
+			seen[column] = true
+
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				textColumns[column] = true
+			}
+		}
+	}
+
+	numericColumns, otherColumns := []string{}, []string{}
+
+	for column := range seen {
+		if textColumns[column] {
+			otherColumns = append(otherColumns, column)
+		} else {
+			numericColumns = append(numericColumns, column)
+		}
+	}
+
+	features := &rowFeatures{ranges: make([]float64, len(numericColumns))}
+	lows, highs := make([]float64, len(numericColumns)), make([]float64, len(numericColumns))
+
+	for i := range numericColumns {
+		lows[i], highs[i] = math.Inf(1), math.Inf(-1)
+	}
+
+	for _, row := range c.dataset {
+		numbers := make([]float64, len(numericColumns))
+		texts := make([]string, len(otherColumns))
+
+		for i, column := range numericColumns {
+			value, ok := row[column]
+			numbers[i] = math.NaN()
+
+			if ok {
+				numbers[i], _ = strconv.ParseFloat(value, 64)
+				lows[i], highs[i] = math.Min(lows[i], numbers[i]), math.Max(highs[i], numbers[i])
+			}
+		}
+
+		for i, column := range otherColumns {
+			texts[i] = row[column]
+		}
+
+		features.numbers = append(features.numbers, numbers)
+		features.texts = append(features.texts, texts)
+	}
+
+	for i := range numericColumns {
+		features.ranges[i] = highs[i] - lows[i]
+	}
+
+	return features
+}
+
+// distance is the mean per-column distance between two dataset rows. Numeric columns contribute their
+// difference scaled by the column's range, other columns 0 when equal and 1 otherwise.
+func (f *rowFeatures) distance(a int, b int) float64 {
+	columns := len(f.ranges) + len(f.texts[a])
+
+	if columns == 0 {
+		return 0
+	}
+
+	total := 0.0
+
+	for i, valueRange := range f.ranges {
+		x, y := f.numbers[a][i], f.numbers[b][i]
+
+		switch {
+		case math.IsNaN(x) || math.IsNaN(y):
+			if math.IsNaN(x) != math.IsNaN(y) {
+				total++
+			}
+		case valueRange > 0:
+			total += math.Abs(x-y) / valueRange
+		}
+	}
+
+	for i, value := range f.texts[a] {
+		if value != f.texts[b][i] {
+			total++
+		}
+	}
+
+	return total / float64(columns)
+}
@@ -0,0 +1,147 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestSamplingPlan(t *testing.T) {
+	dataset := []RowItem{
+		{"x": "0", "class": "a"},
+		{"x": "1", "class": "a"},
+		{"x": "100", "class": "a"},
+		{"x": "5", "class": "b"},
+	}
+
+	newClassifier := func(strategy SamplingStrategy) *TaoClassifier {
+		classifier := newTestClassifier(t, TaoClassifierOptions{TargetColumn: "class", SamplingStrategy: strategy})
+		classifier.dataset = dataset
+
+		return classifier
+	}
+
+	t.Run("Stratified sampling lets the classes take turns. ", func(t *testing.T) {
+		classifier := newClassifier(SamplingStratified)
+		plan := classifier.samplingPlan(classifier.rowsByClass(), []Label{"a", "b"})
+
+		if len(plan) != 4 || plan[0].class != "a" || plan[1].class != "b" || plan[2].class != "a" || plan[3].class != "a" {
+			t.Errorf("Expected a, b, a, a, got %+v", plan)
+		}
+	})
+
+	t.Run("Shuffle sampling uses every row once. ", func(t *testing.T) {
+		classifier := newClassifier(SamplingShuffle)
+		plan := classifier.samplingPlan(classifier.rowsByClass(), []Label{"a", "b"})
+		rows := []int{}
+
+		for _, sample := range plan {
+			rows = append(rows, sample.row)
+
+			if dataset[sample.row]["class"] != sample.class {
+				t.Errorf("Expected row %d to profile its own class, got %v", sample.row, sample.class)
+			}
+		}
+
+		sort.Ints(rows)
+
+		if !reflect.DeepEqual(rows, []int{0, 1, 2, 3}) {
+			t.Errorf("Expected every row once, got %v", rows)
+		}
+	})
+
+	t.Run("Diversity sampling picks the most different row next. ", func(t *testing.T) {
+		classifier := newClassifier(SamplingDiversity)
+
+		for range 10 {
+			order := diversityOrder([]int{0, 1, 2}, classifier.rowFeatures(), 0)
+
+			if len(order) != 3 || (order[0] != 2 && order[1] != 2) {
+				t.Errorf("Expected the outlier among the first two rows, got %v", order)
+			}
+		}
+	})
+
+	t.Run("Diversity sampling picks only as many rows as the budget can use. ", func(t *testing.T) {
+		classifier := newClassifier(SamplingDiversity)
+
+		for range 10 {
+			order := diversityOrder([]int{0, 1, 2}, classifier.rowFeatures(), 2)
+			sorted := append([]int{}, order...)
+			sort.Ints(sorted)
+
+			if !reflect.DeepEqual(sorted, []int{0, 1, 2}) || (order[0] != 2 && order[1] != 2) {
+				t.Errorf("Expected every row with the outlier among the first two, got %v", order)
+			}
+		}
+
+		budgeted := newTestClassifier(t, TaoClassifierOptions{MaxTrainingRows: 20, MaxTrainingCalls: 2, RefineProfiles: true})
+
+		if budget := budgeted.trainingRowBudget(); budget != 10 {
+			t.Errorf("Expected a budget of 10 rows, got %v", budget)
+		}
+	})
+
+	t.Run("Rejects unknown strategies and negative budgets. ", func(t *testing.T) {
+		var configErr *ConfigError
+
+		if _, err := NewTaoClassifier(TaoClassifierOptions{Provider: NewFakeProvider(), SamplingStrategy: "random"}); !errors.As(err, &configErr) {
+			t.Errorf("Expected a *ConfigError, got %v", err)
+		}
+
+		if _, err := NewTaoClassifier(TaoClassifierOptions{Provider: NewFakeProvider(), MaxTrainingRows: -1}); !errors.As(err, &configErr) || configErr.Field != "MaxTrainingRows" {
+			t.Errorf("Expected a MaxTrainingRows *ConfigError, got %v", err)
+		}
+
+		if _, err := NewTaoClassifier(TaoClassifierOptions{Provider: NewFakeProvider(), MaxTrainingCalls: -1}); !errors.As(err, &configErr) || configErr.Field != "MaxTrainingCalls" {
+			t.Errorf("Expected a MaxTrainingCalls *ConfigError, got %v", err)
+		}
+	})
+}
+
+func TestTrainBudgets(t *testing.T) {
+	dataset := []RowItem{}
+
+	for i := range 2000 {
+		dataset = append(dataset, RowItem{"id": fmt.Sprint(i), "class": []string{"low", "medium", "high"}[i%3]})
+	}
+
+	train := func(t *testing.T, options TaoClassifierOptions) int {
+		provider := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			label := profileLabelPattern.FindStringSubmatch(request.LastUserMessage())[1]
+			return fmt.Sprintf(`{"label": "%s", "description": ["a description"]}`, label), nil
+		})
+
+		options.Provider = provider
+		options.TargetColumn = "class"
+
+		classifier := newTestClassifier(t, options)
+		classifier.dataset = dataset
+
+		if err := classifier.Train(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		return len(provider.Requests())
+	}
+
+	t.Run("Stops once every class has enough descriptions. ", func(t *testing.T) {
+		for _, strategy := range []SamplingStrategy{SamplingStratified, SamplingShuffle, SamplingDiversity} {
+			if calls := train(t, TaoClassifierOptions{PromptSampleSize: 2, SamplingStrategy: strategy}); calls != 6 {
+				t.Errorf("Expected 6 calls with %v sampling, got %v", strategy, calls)
+			}
+		}
+	})
+
+	t.Run("Stops at the row and call budgets. ", func(t *testing.T) {
+		if calls := train(t, TaoClassifierOptions{PromptSampleSize: 100, MaxTrainingCalls: 5}); calls != 5 {
+			t.Errorf("Expected 5 calls, got %v", calls)
+		}
+
+		if calls := train(t, TaoClassifierOptions{PromptSampleSize: 100, MaxTrainingRows: 7}); calls != 7 {
+			t.Errorf("Expected 7 calls, got %v", calls)
+		}
+	})
+}
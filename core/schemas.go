@@ -0,0 +1,102 @@
+package core
+
+// labelSchema restricts a JSON string to the known labels, any string is allowed when none are known.
+func (c *TaoClassifier) labelSchema() map[string]interface{} {
+	labels, _ := c.GetAvailableLabels()
+
+	if len(labels) == 0 {
+		return map[string]interface{}{"type": "string"}
+	}
+
+	return map[string]interface{}{"type": "string", "enum": labels}
+}
+
+// classificationSchema is the structured output of PredictOne: { predicted_class, probability, distribution }.
+func (c *TaoClassifier) classificationSchema() *ResponseSchema {
+	properties := map[string]interface{}{
+		"predicted_class": c.labelSchema(),
+		"probability":     map[string]interface{}{"type": "number"},
+	}
+
+	required := []string{"predicted_class", "probability"}
+
+	labels, _ := c.GetAvailableLabels()
+
+	if len(labels) > 0 {
+		// strict schemas can't have free-form keys, so every label is a property
+		scores := map[string]interface{}{}
+
+		for _, label := range labels {
+			scores[label] = map[string]interface{}{"type": "number"}
+		}
+
+		properties["distribution"] = map[string]interface{}{
+			"type":                 "object",
+			"properties":           scores,
+			"required":             labels,
+			"additionalProperties": false,
+		}
+
+		required = append(required, "distribution")
+	}
+
+	return &ResponseSchema{
+		Name: "classification",
+		Schema: map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		},
+		Strict: true,
+	}
+}
+
+// batchClassificationSchema is the structured output of a batched prompt. Structured outputs need an
+// object at the root, so the predictions are wrapped in { predictions: [...] }.
+func (c *TaoClassifier) batchClassificationSchema() *ResponseSchema {
+	return &ResponseSchema{
+		Name: "batch_classification",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"predictions": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"id":              map[string]interface{}{"type": "string"},
+							"predicted_class": c.labelSchema(),
+							"probability":     map[string]interface{}{"type": "number"},
+						},
+						"required":             []string{"id", "predicted_class", "probability"},
+						"additionalProperties": false,
+					},
+				},
+			},
+			"required":             []string{"predictions"},
+			"additionalProperties": false,
+		},
+		Strict: true,
+	}
+}
+
+// profileSchema is the structured output of GenerateClassifierProfile: { label, description }.
+func profileSchema() *ResponseSchema {
+	return &ResponseSchema{
+		Name: "classifier_profile",
+		Schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"label": map[string]interface{}{"type": "string"},
+				"description": map[string]interface{}{
+					"type":  "array",
+					"items": map[string]interface{}{"type": "string"},
+				},
+			},
+			"required":             []string{"label", "description"},
+			"additionalProperties": false,
+		},
+		Strict: true,
+	}
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStructuredOutputs(t *testing.T) {
+	t.Run("PredictOne asks for a schema restricting the class to the known labels. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"predicted_class": "dog", "probability": 0.9}`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider})
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"dog": {"barks"},
+			"cat": {"meows"},
+		})
+
+		_, err := classifier.PredictOne("Woof")
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		schema := provider.Requests()[0].ResponseSchema
+
+		if schema == nil || !schema.Strict {
+			t.Fatalf("Expected a strict response schema, got %v", schema)
+		}
+
+		properties := schema.Schema["properties"].(map[string]interface{})
+		predictedClass := properties["predicted_class"].(map[string]interface{})
+
+		if !reflect.DeepEqual(predictedClass["enum"], []string{"cat", "dog"}) {
+			t.Errorf("Expected the labels as enum, got %v", predictedClass["enum"])
+		}
+	})
+
+	t.Run("GenerateClassifierProfile asks for the profile schema. ", func(t *testing.T) {
+		provider := newFakeClassifierProvider()
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider})
+
+		_, err := classifier.GenerateClassifierProfile("cat", RowItem{"sound": "meow"}, ClassifierProfile{})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		schema := provider.Requests()[0].ResponseSchema
+
+		if schema == nil || schema.Name != "classifier_profile" {
+			t.Errorf("Expected the classifier_profile schema, got %v", schema)
+		}
+	})
+
+	t.Run("GenerateObject passes JSON Schemas to the provider but not informal schemas. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"name": "Tao"}`, `{"name": "Tao"}`)
+		ai := NewAIWithProvider(provider)
+
+		_, err := ai.GenerateObject("Generate an object.", `{"type": "object", "properties": {"name": {"type": "string"}}}`)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		_, err = ai.GenerateObject("Generate an object.", `{"name": "string"}`)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		requests := provider.Requests()
+
+		if requests[0].ResponseSchema == nil || requests[0].ResponseSchema.Strict {
+			t.Errorf("Expected a non-strict response schema, got %v", requests[0].ResponseSchema)
+		}
+
+		if requests[1].ResponseSchema != nil {
+			t.Errorf("Expected no response schema, got %v", requests[1].ResponseSchema)
+		}
+	})
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"fmt"
+)
+
+// defaultSelfConsistencyTemperature is used for the samples when the classifier's Temperature is 0, identical
+// samples would make the vote pointless.
+const defaultSelfConsistencyTemperature = 0.7
+
+// predictSelfConsistent draws up to c.selfConsistencySamples predictions with different seeds and returns the
+// majority label. Probability and Distribution are the vote shares and Votes the vote counts. Sampling stops
+// early when the first c.selfConsistencyStop samples agree. Samples that fail to parse or predict an unknown
+// label don't vote, the prediction only fails when no sample votes.
+func (c *TaoClassifier) predictSelfConsistent(ctx context.Context, text string) (ClassificationResult, error) {
+	temperature := c.temperature
+
+	if temperature <= 0 {
+		temperature = defaultSelfConsistencyTemperature
+	}
+
+	votes := map[Label]int{}
+	confidence := map[Label]float64{}
+	samples := 0
+	var lastErr error
+
+	for i := 0; i < c.selfConsistencySamples; i++ {
+		result, err := c.predictSample(ctx, text, GenerateTextOptions{Temperature: temperature, Seed: int64(i + 1)}, false)
+
+		if err != nil {
+			category := CategorizeError(err)
+
+			if category != ErrorCategoryParse && category != ErrorCategoryValidation {
+				return ClassificationResult{Label: "", Probability: -1}, err
+			}
+
+			lastErr = err
+		} else {
+			label := fmt.Sprint(result.PredictedClass)
+			votes[label]++
+			confidence[label] += result.Probability
+			samples++
+		}
+
+		if c.selfConsistencyStop > 0 && i+1 == c.selfConsistencyStop && len(votes) == 1 && samples == c.selfConsistencyStop {
+			break
+		}
+	}
+
+	if samples == 0 {
+		return ClassificationResult{Label: "", Probability: -1}, lastErr
+	}
+
+	// ties go to the label the samples were more confident about, then to the first label alphabetically
+	winner := ""
+
+	for label, count := range votes {
+		if winner == "" || count > votes[winner] ||
+			(count == votes[winner] && (confidence[label] > confidence[winner] || (confidence[label] == confidence[winner] && label < winner))) {
+			winner = label
+		}
+	}
+
+	labels, _ := c.GetAvailableLabels()
+	distribution := map[Label]float64{}
+
+	for _, label := range labels {
+		distribution[label] = 0
+	}
+
+	for label, count := range votes {
+		distribution[label] = float64(count) / float64(samples)
+	}
+
+	result := ClassificationResult{
+		PredictedClass: winner,
+		Probability:    distribution[winner],
+		Distribution:   distribution,
+		Votes:          votes,
+	}
+
+	if c.targetColumn != "" {
+		result.Label = c.targetColumn
+	}
+
+	return result, nil
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestSelfConsistency(t *testing.T) {
+	prompts := map[Label][]LabelDescription{
+		"positive": {"positive sentiment"},
+		"neutral":  {"neutral sentiment"},
+		"negative": {"negative sentiment"},
+	}
+
+	t.Run("Reports the majority label with its vote share. ", func(t *testing.T) {
+		provider := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			if request.Seed%2 == 0 {
+				return `{"predicted_class": "neutral", "probability": 0.9}`, nil
+			}
+
+			return `{"predicted_class": "positive", "probability": 0.6}`, nil
+		})
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, SelfConsistency: 5, Temperature: 0.5})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Not bad I guess")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.PredictedClass != "positive" || math.Abs(result.Probability-0.6) > 1e-9 {
+			t.Errorf("Expected positive with probability 0.6, got %v with %v", result.PredictedClass, result.Probability)
+		}
+
+		if result.Votes["positive"] != 3 || result.Votes["neutral"] != 2 || result.Distribution["negative"] != 0 {
+			t.Errorf("Expected 3 votes for positive and 2 for neutral, got %v (%v)", result.Votes, result.Distribution)
+		}
+
+		requests := provider.Requests()
+
+		if len(requests) != 5 || requests[0].Seed == requests[1].Seed || requests[0].Temperature != 0.5 {
+			t.Errorf("Expected 5 samples with different seeds at temperature 0.5, got %+v", requests)
+		}
+	})
+
+	t.Run("Stops early when the first samples agree. ", func(t *testing.T) {
+		sample := `{"predicted_class": "positive", "probability": 0.8}`
+		provider := NewFakeProvider(sample, sample, sample, sample, sample)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, SelfConsistency: 5, SelfConsistencyStop: 3})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Great game!")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(provider.Requests()) != 3 || result.Probability != 1 || result.Votes["positive"] != 3 {
+			t.Errorf("Expected 3 unanimous samples, got %v requests and %v", len(provider.Requests()), result)
+		}
+	})
+
+	t.Run("Keeps sampling when the first samples disagree. ", func(t *testing.T) {
+		provider := NewFakeProvider(
+			`{"predicted_class": "positive", "probability": 0.8}`,
+			`{"predicted_class": "negative", "probability": 0.7}`,
+			`{"predicted_class": "positive", "probability": 0.8}`,
+			`{"predicted_class": "positive", "probability": 0.8}`,
+		)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, SelfConsistency: 4, SelfConsistencyStop: 2})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Great game?")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if len(provider.Requests()) != 4 || result.PredictedClass != "positive" || result.Probability != 0.75 {
+			t.Errorf("Expected positive with 3 of 4 votes, got %v requests and %v", len(provider.Requests()), result)
+		}
+	})
+
+	t.Run("Ignores samples that fail to parse or validate. ", func(t *testing.T) {
+		provider := NewFakeProvider(
+			`not json`,
+			`{"predicted_class": "sarcastic", "probability": 0.9}`,
+			`{"predicted_class": "negative", "probability": 0.7}`,
+		)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, SelfConsistency: 3})
+		classifier.PromptTrain(prompts)
+
+		result, err := classifier.PredictOne("Oh great, another Monday")
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if result.PredictedClass != "negative" || result.Probability != 1 {
+			t.Errorf("Expected negative from the only valid sample, got %v with %v", result.PredictedClass, result.Probability)
+		}
+
+		sample := `{"predicted_class": "sarcastic", "probability": 0.9}`
+		provider = NewFakeProvider(sample, sample)
+
+		classifier = newTestClassifier(t, TaoClassifierOptions{Provider: provider, SelfConsistency: 2})
+		classifier.PromptTrain(prompts)
+
+		var validationErr *ValidationError
+
+		if _, err := classifier.PredictOne("Oh great, another Monday"); !errors.As(err, &validationErr) {
+			t.Errorf("Expected a *ValidationError when no sample votes, got %v", err)
+		}
+	})
+
+	t.Run("Rejects invalid self-consistency options. ", func(t *testing.T) {
+		var configErr *ConfigError
+
+		_, err := NewTaoClassifier(TaoClassifierOptions{Provider: NewFakeProvider(), SelfConsistency: 3, ScoringMode: ScoringLogprobs})
+
+		if !errors.As(err, &configErr) || configErr.Field != "SelfConsistency" {
+			t.Errorf("Expected a SelfConsistency *ConfigError, got %v", err)
+		}
+
+		_, err = NewTaoClassifier(TaoClassifierOptions{Provider: NewFakeProvider(), SelfConsistency: 3, SelfConsistencyStop: 4})
+
+		if !errors.As(err, &configErr) || configErr.Field != "SelfConsistencyStop" {
+			t.Errorf("Expected a SelfConsistencyStop *ConfigError, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+const (
+	maxContrastRows     = 3 // rows of other classes shown for contrast in one profile prompt
+	refineBatchRows     = 5 // rows of a class sent with one refinement call
+	defaultRefineRounds = 3
+)
+
+// TrainProgress is reported through TaoClassifierOptions.OnTrainProgress when a class is done, i.e. it has
+// enough descriptions, ran out of rows or a training budget is used up.
+type TrainProgress struct {
+	Class        Label // class that was profiled
+	ClassIndex   int   // 1 for the first class that is done
+	Classes      int   // number of classes to profile
+	Rows         int   // rows of the class used to generate profiles
+	Descriptions int   // descriptions of the class after training
+	Calls        int   // profile calls made by Train so far
+}
+
+// rowsByClass groups the indexes of the dataset rows by target class, see rowClasses. In multi-label mode a
+// row belongs to every label in its target column.
+func (c *TaoClassifier) rowsByClass() map[Label][]int {
+	groups := map[Label][]int{}
+
+	for index, row := range c.dataset {
+		for _, class := range c.rowClasses(row) {
+			groups[class] = append(groups[class], index)
+		}
+	}
+
+	return groups
+}
+
+// contrastRows picks one random row from up to maxContrastRows other classes. With MaxTrainingRows only
+// rows that were already selected for training are shown.
+func (c *TaoClassifier) contrastRows(groups map[Label][]int, class Label, selectedRows map[int]bool) []RowItem {
+	candidates := map[Label][]int{}
+	others := []Label{}
+
+	for other, rows := range groups {
+		if other == class {
+			continue
+		}
+
+		for _, row := range rows {
+			if c.maxTrainingRows == 0 || selectedRows[row] {
+				candidates[other] = append(candidates[other], row)
+			}
+		}
+
+		if len(candidates[other]) > 0 {
+			others = append(others, other)
+		}
+	}
+
+	sort.Strings(others)
+	rand.Shuffle(len(others), func(i, j int) { others[i], others[j] = others[j], others[i] })
+
+	rows := []RowItem{}
+
+	for _, other := range others[:min(len(others), maxContrastRows)] {
+		rows = append(rows, c.dataset[candidates[other][rand.Intn(len(candidates[other]))]])
+	}
+
+	return rows
+}
+
+// formatRowItems formats rows for a prompt, one "key: value" line per column with the keys sorted.
+func formatRowItems(rows []RowItem) string {
+	formatted := []string{}
+
+	for _, row := range rows {
+		keys := []string{}
+
+		for key := range row {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		lines := ""
+
+		for _, key := range keys {
+			lines += key + ": " + row[key] + "\n"
+		}
+
+		formatted = append(formatted, lines)
+	}
+
+	return strings.Join(formatted, "\n")
+}
+
+// consolidateDescriptions drops empty and duplicate descriptions, ignoring case and whitespace, and keeps at
+// most limit of them.
+func consolidateDescriptions(descriptions []LabelDescription, limit int) []LabelDescription {
+	consolidated := []LabelDescription{}
+	seen := map[string]bool{}
+
+	for _, description := range descriptions {
+		description = strings.TrimSpace(description)
+		key := normalizeLabel(description)
+
+		if key == "" || seen[key] {
+			continue
+		}
+
+		seen[key] = true
+		consolidated = append(consolidated, description)
+	}
+
+	if limit > 0 && len(consolidated) > limit {
+		consolidated = consolidated[:limit]
+	}
+
+	return consolidated
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestTrainByClass(t *testing.T) {
+	datasetPath := filepath.Join(t.TempDir(), "support.csv")
+	rows := "hours,support\n1,Low\n2,Low\n3,Low\n10,High\n11,High\n5,Medium\n"
+
+	if err := os.WriteFile(datasetPath, []byte(rows), 0644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// answers with the target column value of the first row item so the test can see which rows were used
+	newProvider := func() *FakeProvider {
+		return NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			matches := profileLabelPattern.FindStringSubmatch(request.LastUserMessage())
+
+			if len(matches) < 2 {
+				return "", fmt.Errorf("no label in prompt")
+			}
+
+			rowItems := strings.SplitN(request.LastUserMessage(), "row items: ", 2)[1]
+			support := strings.SplitN(strings.SplitN(rowItems, "support: ", 2)[1], "\n", 2)[0]
+
+			return fmt.Sprintf(`{"label": "%s", "description": ["from a %s row"]}`, matches[1], support), nil
+		})
+	}
+
+	t.Run("Profiles every class from its own rows and reports progress. ", func(t *testing.T) {
+		progress := []TrainProgress{}
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{
+			Provider:            newProvider(),
+			TrainingDatasetPath: datasetPath,
+			TargetColumn:        "support",
+			PromptSampleSize:    2,
+			OnTrainProgress:     func(p TrainProgress) { progress = append(progress, p) },
+		})
+
+		if err := classifier.Train(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		prompts := classifier.GetSavableModel().Prompts
+		expected := map[Label][]LabelDescription{
+			"Low":    {"from a Low row", "from a Low row"},
+			"High":   {"from a High row", "from a High row"},
+			"Medium": {"from a Medium row"},
+		}
+
+		if !reflect.DeepEqual(prompts, expected) {
+			t.Errorf("Expected profiles from rows of the same class, got %v", prompts)
+		}
+
+		// the classes take turns, Medium runs out of rows first
+		expectedProgress := []TrainProgress{
+			{Class: "Medium", ClassIndex: 1, Classes: 3, Rows: 1, Descriptions: 1, Calls: 3},
+			{Class: "High", ClassIndex: 2, Classes: 3, Rows: 2, Descriptions: 2, Calls: 4},
+			{Class: "Low", ClassIndex: 3, Classes: 3, Rows: 2, Descriptions: 2, Calls: 5},
+		}
+
+		if !reflect.DeepEqual(progress, expectedProgress) {
+			t.Errorf("Expected progress for every class, got %+v", progress)
+		}
+	})
+
+	t.Run("Shows rows of other classes for contrast. ", func(t *testing.T) {
+		provider := newProvider()
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{
+			Provider:            provider,
+			TrainingDatasetPath: datasetPath,
+			TargetColumn:        "support",
+			PromptSampleSize:    1,
+			ContrastiveTraining: true,
+		})
+
+		if err := classifier.Train(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		for _, request := range provider.Requests() {
+			prompt := request.LastUserMessage()
+			label := profileLabelPattern.FindStringSubmatch(prompt)[1]
+			contrast := strings.SplitN(prompt, "For contrast", 2)
+
+			if len(contrast) != 2 || strings.Contains(contrast[1], "support: "+label+"\n") || strings.Count(contrast[1], "support: ") != 2 {
+				t.Errorf("Expected one row of each other class for contrast, got %v", prompt)
+			}
+		}
+	})
+
+	t.Run("Trims class names and skips rows without a class. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: newProvider(), TargetColumn: "support", PromptSampleSize: 1})
+		classifier.dataset = []RowItem{
+			{"hours": "1", "support": " Low"},
+			{"hours": "2", "support": ""},
+			{"hours": "10", "support": "High "},
+		}
+		classifier.initializePromptsFromDataset()
+
+		if err := classifier.Train(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if labels, _ := classifier.GetAvailableLabels(); !reflect.DeepEqual(labels, []Label{"High", "Low"}) {
+			t.Errorf("Expected the trimmed classes, got %v", labels)
+		}
+
+		if _, err := classifier.ArePromptsLoaded(); err != nil {
+			t.Errorf("Expected every class to have descriptions, got %v", err)
+		}
+	})
+
+	t.Run("Fails when a class gets no descriptions. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{
+			Provider:            newProvider(),
+			TrainingDatasetPath: datasetPath,
+			TargetColumn:        "support",
+			MaxTrainingCalls:    2,
+		})
+
+		if err := classifier.Train(); err == nil || !strings.Contains(err.Error(), "no descriptions") {
+			t.Errorf("Expected an error for the class left without descriptions, got %v", err)
+		}
+	})
+
+	t.Run("Shows only rows within MaxTrainingRows for contrast. ", func(t *testing.T) {
+		provider := newProvider()
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{
+			Provider:            provider,
+			TrainingDatasetPath: datasetPath,
+			TargetColumn:        "support",
+			PromptSampleSize:    1,
+			ContrastiveTraining: true,
+			MaxTrainingRows:     3,
+		})
+
+		classifier.Train()
+
+		shown := map[string]bool{}
+
+		for _, request := range provider.Requests() {
+			for _, row := range regexp.MustCompile(`hours: \d+`).FindAllString(request.LastUserMessage(), -1) {
+				shown[row] = true
+			}
+		}
+
+		if len(shown) > 3 {
+			t.Errorf("Expected at most 3 distinct rows in the prompts, got %v", shown)
+		}
+	})
+
+	t.Run("Groups rows by every label in multi-label mode. ", func(t *testing.T) {
+		classifier := newTestClassifier(t, TaoClassifierOptions{MultiLabel: true, TargetColumn: "tags"})
+		classifier.dataset = []RowItem{
+			{"text": "a", "tags": "billing|bug"},
+			{"text": "b", "tags": "bug"},
+			{"text": "c", "tags": ""},
+		}
+
+		groups := classifier.rowsByClass()
+
+		if !reflect.DeepEqual(groups["billing"], []int{0}) || !reflect.DeepEqual(groups["bug"], []int{0, 1}) || len(groups) != 2 {
+			t.Errorf("Expected 1 billing and 2 bug rows, got %v", groups)
+		}
+	})
+}
+
+func TestRefineProfiles(t *testing.T) {
+	t.Run("Rewrites the current profile into a consolidated one. ", func(t *testing.T) {
+		provider := NewFakeProvider(`{"label": "High", "description": ["Studies a lot", "studies  a lot", "Attends class", "Has support at home", ""]}`)
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, PromptSampleSize: 2})
+
+		profile, err := classifier.GenerateClassifierProfile("High", RowItem{"hours": "20"}, ClassifierProfile{Label: "High", Description: []string{"Studies a lot"}})
+
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if !reflect.DeepEqual(profile.Description, []LabelDescription{"Studies a lot", "Attends class"}) {
+			t.Errorf("Expected 2 deduplicated descriptions, got %v", profile.Description)
+		}
+
+		if prompt := provider.Requests()[0].LastUserMessage(); !strings.Contains(prompt, "- Studies a lot") || !strings.Contains(prompt, "at most 2 descriptions") {
+			t.Errorf("Expected the current profile in the prompt, got %v", prompt)
+		}
+	})
+
+	t.Run("Train refines every class in batches instead of appending. ", func(t *testing.T) {
+		dataset := []RowItem{}
+
+		for i := range 12 {
+			dataset = append(dataset, RowItem{"hours": fmt.Sprint(i), "support": "High"})
+		}
+
+		provider := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			return fmt.Sprintf(`{"label": "High", "description": ["Studies a lot", "Studies a lot", "Seen %d rows", "Attends class"]}`, strings.Count(request.LastUserMessage(), "support: High")), nil
+		})
+
+		progress := []TrainProgress{}
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{
+			Provider:         provider,
+			TargetColumn:     "support",
+			PromptSampleSize: 2,
+			RefineProfiles:   true,
+			RefineRounds:     2,
+			OnTrainProgress:  func(p TrainProgress) { progress = append(progress, p) },
+		})
+		classifier.dataset = dataset
+
+		if err := classifier.Train(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		requests := provider.Requests()
+
+		if len(requests) != 2 || !strings.Contains(requests[1].LastUserMessage(), "- Seen 5 rows") {
+			t.Errorf("Expected 2 rounds, the second one with the first profile, got %v", requests)
+		}
+
+		if descriptions := classifier.GetSavableModel().Prompts["High"]; !reflect.DeepEqual(descriptions, []LabelDescription{"Studies a lot", "Seen 5 rows"}) {
+			t.Errorf("Expected the profile of the last round, got %v", descriptions)
+		}
+
+		if len(progress) != 1 || progress[0].Rows != 10 || progress[0].Calls != 2 {
+			t.Errorf("Expected 10 rows in 2 calls, got %+v", progress)
+		}
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"math/rand"
 	"os"
 	"regexp"
@@ -12,35 +13,170 @@ import (
 
 type RowItem = map[string]string
 
+var markdownJsonPattern = regexp.MustCompile("(?s)```(?:json)?\\s*(.*?)\\s*```")
+
+// requiredJsonKeyer is implemented by the response types parsed with CleanGPTJson. An object is only
+// accepted as T when it has all of these keys, so an unrelated object in the output isn't mistaken for it.
+type requiredJsonKeyer interface {
+	requiredJsonKeys() []string
+}
+
+// CleanGPTJson parses model output into T. Besides plain JSON it accepts JSON inside markdown fences
+// or surrounded by text, and raw newlines inside string values. A truncated value is a *ParseError.
 func CleanGPTJson[T any](jsonStr string) (T, error) {
 	var result T
 
 	if jsonStr == "" {
-		return result, fmt.Errorf("input JSON string is empty")
+		return result, &ParseError{Raw: jsonStr, Err: fmt.Errorf("input JSON string is empty")}
 	}
 
-	// Regular expression to capture JSON content inside ```json ... ```
-	re := regexp.MustCompile("(?s).*?```json\\s*(.*?)\\s*```.*")
-	matches := re.FindStringSubmatch(jsonStr)
-	if len(matches) >= 2 {
-		jsonStr = matches[1]
+	var err error
+
+	for _, candidate := range jsonCandidates(jsonStr) {
+		var parsed T
+
+		candidate = escapeJsonStringControls(candidate)
+		err = json.Unmarshal([]byte(candidate), &parsed)
+
+		if err == nil {
+			err = checkRequiredJsonKeys(parsed, candidate)
+		}
+
+		if err == nil {
+			return parsed, nil
+		}
 	}
 
-	// Remove the ```json\n at the beginning and \n``` at the end
-	re2 := regexp.MustCompile("^```json\\n|\\n```$")
-	jsonStr = re2.ReplaceAllString(jsonStr, "")
+	return result, &ParseError{Raw: jsonStr, Err: err}
+}
 
-	// Remove all newlines and carriage returns
-	jsonStr = strings.ReplaceAll(jsonStr, "\n", "")
-	jsonStr = strings.ReplaceAll(jsonStr, "\r", "")
+// checkRequiredJsonKeys returns an error when parsed is a requiredJsonKeyer and candidate misses one of its keys.
+func checkRequiredJsonKeys(parsed any, candidate string) error {
+	keyer, ok := parsed.(requiredJsonKeyer)
 
-	// Unmarshal the cleaned JSON string into the provided type
-	err := json.Unmarshal([]byte(jsonStr), &result)
-	if err != nil {
-		return result, err
+	if !ok {
+		return nil
+	}
+
+	object := map[string]json.RawMessage{}
+
+	if err := json.Unmarshal([]byte(candidate), &object); err != nil {
+		return err
+	}
+
+	for _, key := range keyer.requiredJsonKeys() {
+		if _, ok := object[key]; !ok {
+			return fmt.Errorf("missing required key %q", key)
+		}
+	}
+
+	return nil
+}
+
+// jsonCandidates lists the substrings of text that may hold the JSON value, most likely first:
+// the whole text, the contents of markdown code fences, then every outermost balanced {...} or [...].
+// Values nested inside another one are never candidates, and nothing after a bracket that's never closed
+// is either, since it's part of a truncated value.
+func jsonCandidates(text string) []string {
+	candidates := []string{strings.TrimSpace(text)}
+
+	for _, match := range markdownJsonPattern.FindAllStringSubmatch(text, -1) {
+		candidates = append(candidates, match[1])
+	}
+
+	for start := 0; start < len(text); start++ {
+		if text[start] != '{' && text[start] != '[' {
+			continue
+		}
+
+		end := balancedJsonEnd(text, start)
+
+		if end == -1 {
+			break
+		}
+
+		candidates = append(candidates, text[start:end])
+		start = end - 1
 	}
 
-	return result, nil
+	return candidates
+}
+
+// balancedJsonEnd returns the index after the bracket closing the one at start, ignoring brackets
+// inside strings, or -1 if it's never closed.
+func balancedJsonEnd(text string, start int) int {
+	depth := 0
+	inString := false
+	escaped := false
+
+	for i := start; i < len(text); i++ {
+		char := text[i]
+
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case char == '\\':
+				escaped = true
+			case char == '"':
+				inString = false
+			}
+
+			continue
+		}
+
+		switch char {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+
+	return -1
+}
+
+// escapeJsonStringControls escapes raw newlines, carriage returns and tabs inside JSON strings,
+// which models sometimes emit but JSON doesn't allow.
+func escapeJsonStringControls(jsonStr string) string {
+	var builder strings.Builder
+
+	inString := false
+	escaped := false
+
+	for _, char := range jsonStr {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case char == '\\':
+				escaped = true
+			case char == '"':
+				inString = false
+			case char == '\n':
+				builder.WriteString(`\n`)
+				continue
+			case char == '\r':
+				builder.WriteString(`\r`)
+				continue
+			case char == '\t':
+				builder.WriteString(`\t`)
+				continue
+			}
+		} else if char == '"' {
+			inString = true
+		}
+
+		builder.WriteRune(char)
+	}
+
+	return builder.String()
 }
 
 func ReadCSVFile(filePath string) ([]RowItem, error) {
@@ -60,10 +196,14 @@ func ReadCSVFile(filePath string) ([]RowItem, error) {
 
 	for {
 		record, err := reader.Read()
-		if err != nil {
+		if err == io.EOF {
 			break
 		}
 
+		if err != nil {
+			return nil, fmt.Errorf("ReadCSVFile: %w", err)
+		}
+
 		recordMap := RowItem{}
 		for i, header := range headers {
 			recordMap[header] = record[i]
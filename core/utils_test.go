@@ -1,6 +1,9 @@
 package core
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -66,6 +69,63 @@ func TestCleanGPTJSON(t *testing.T) {
 
 	})
 
+	t.Run("Keeps newlines inside string values", func(t *testing.T) {
+		result, err := CleanGPTJson[map[string]string]("{\"description\": \"line one\nline two\"}")
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if result["description"] != "line one\nline two" {
+			t.Errorf("Expected the newline to be kept, got %q", result["description"])
+		}
+	})
+
+	t.Run("Finds JSON in chatty responses without markdown", func(t *testing.T) {
+		llmResponse := `Sure! Based on the descriptions {"predicted_class": "cat", "probability": 0.9} is my answer, since "meow" {is} a cat sound.`
+
+		result, err := CleanGPTJson[ClassificationResult](llmResponse)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if result.PredictedClass != "cat" {
+			t.Errorf("Expected cat, got %v", result.PredictedClass)
+		}
+	})
+
+	t.Run("Ignores brackets inside strings", func(t *testing.T) {
+		result, err := CleanGPTJson[[]string]("Here you go: [\"a ] b\", \"c\"] done")
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		if len(result) != 2 || result[0] != "a ] b" {
+			t.Errorf("Expected 2 items, got %v", result)
+		}
+	})
+
+	t.Run("Rejects objects nested inside a truncated reply", func(t *testing.T) {
+		_, err := CleanGPTJson[interface{}](`{"name":"Tao","attributes":{"a":"b"}`)
+
+		var parseErr *ParseError
+
+		if !errors.As(err, &parseErr) {
+			t.Errorf("Expected a *ParseError, got %v", err)
+		}
+	})
+
+	t.Run("Rejects objects without the required keys", func(t *testing.T) {
+		if _, err := CleanGPTJson[distributionCheck]("Format: {\"note\": 1}"); err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+
+		if _, err := CleanGPTJson[ClassificationResult](`{"label": "cat"}`); err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+	})
 }
 
 func TestReadCSVFile(t *testing.T) {
@@ -83,6 +143,26 @@ func TestReadCSVFile(t *testing.T) {
 		}
 	})
 
+	t.Run("Malformed row", func(t *testing.T) {
+		filePath := filepath.Join(t.TempDir(), "malformed.csv")
+
+		if err := os.WriteFile(filePath, []byte("a,label\n1,x\n2,y,extra\n3,z\n"), 0644); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		if _, err := ReadCSVFile(filePath); err == nil {
+			t.Errorf("Expected an error, got nil")
+		}
+
+		var configErr *ConfigError
+
+		_, err := NewTaoClassifier(TaoClassifierOptions{Provider: NewFakeProvider(), TrainingDatasetPath: filePath, TargetColumn: "label"})
+
+		if !errors.As(err, &configErr) || configErr.Field != "TrainingDatasetPath" {
+			t.Errorf("Expected a TrainingDatasetPath *ConfigError, got %v", err)
+		}
+	})
+
 	t.Run("Valid CSV file", func(t *testing.T) {
 		filePath := "../datasets/student_performance.csv"
 		records, err := ReadCSVFile(filePath)
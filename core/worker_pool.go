@@ -0,0 +1,73 @@
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+// runConcurrently calls work for every index on up to concurrency workers and waits for them to finish.
+// Once ctx is done the remaining indexes are not started.
+func runConcurrently(ctx context.Context, count int, concurrency int, work func(ctx context.Context, index int)) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	concurrency = min(concurrency, count)
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range concurrency {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for index := range indexes {
+				work(ctx, index)
+			}
+		}()
+	}
+
+feed:
+	for index := range count {
+		select {
+		case <-ctx.Done():
+			break feed
+		case indexes <- index:
+		}
+	}
+
+	close(indexes)
+	wg.Wait()
+}
+
+// predictConcurrently runs predict for every index on up to concurrency workers. Items keep the input order.
+// Once ctx is done the remaining items are not started, they fail with ctx.Err() which is also returned.
+func predictConcurrently(ctx context.Context, count int, concurrency int, predict func(ctx context.Context, index int) (ClassificationResult, error)) (BatchResult, error) {
+	results := make([]ClassificationResult, count)
+	errs := make([]error, count)
+	started := make([]bool, count)
+
+	runConcurrently(ctx, count, concurrency, func(ctx context.Context, index int) {
+		started[index] = true
+		results[index], errs[index] = predict(ctx, index)
+	})
+
+	return finishBatch(ctx, results, errs, started)
+}
+
+// finishBatch marks the items that never started as cancelled when ctx is done and builds the BatchResult.
+func finishBatch(ctx context.Context, results []ClassificationResult, errs []error, started []bool) (BatchResult, error) {
+	err := ctx.Err()
+
+	if err != nil {
+		for index := range errs {
+			if !started[index] && errs[index] == nil {
+				errs[index] = err
+			}
+		}
+	}
+
+	return newBatchResult(results, errs), err
+}
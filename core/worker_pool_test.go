@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPredictConcurrently(t *testing.T) {
+	t.Run("Keeps input order and bounds the number of workers. ", func(t *testing.T) {
+		var mu sync.Mutex
+		running, maxRunning := 0, 0
+
+		batch, err := predictConcurrently(context.Background(), 20, 4, func(ctx context.Context, index int) (ClassificationResult, error) {
+			mu.Lock()
+			running++
+			maxRunning = max(maxRunning, running)
+			mu.Unlock()
+
+			time.Sleep(time.Duration(20-index) * time.Millisecond) // later items finish first
+
+			mu.Lock()
+			running--
+			mu.Unlock()
+
+			return ClassificationResult{PredictedClass: fmt.Sprint(index), Probability: 1}, nil
+		})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		for index, result := range batch.Results() {
+			if result.PredictedClass != fmt.Sprint(index) {
+				t.Errorf("Expected result %d at index %d, got %v", index, index, result.PredictedClass)
+			}
+		}
+
+		if maxRunning > 4 {
+			t.Errorf("Expected at most 4 concurrent predictions, got %v", maxRunning)
+		}
+	})
+
+	t.Run("Reports per-item errors without aborting the batch. ", func(t *testing.T) {
+		itemErr := &ProviderError{Provider: "FakeProvider", StatusCode: 503}
+
+		batch, err := predictConcurrently(context.Background(), 5, 2, func(ctx context.Context, index int) (ClassificationResult, error) {
+			if index == 1 || index == 3 {
+				return ClassificationResult{}, itemErr
+			}
+
+			return ClassificationResult{PredictedClass: "ok", Probability: 0.9}, nil
+		})
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		failed := batch.Errors()
+
+		if len(failed) != 2 || failed[0].Index != 1 || failed[1].Index != 3 {
+			t.Errorf("Expected errors for items 1 and 3, got %v", failed)
+		}
+
+		if !errors.Is(failed[0].Err, itemErr) || failed[0].ErrorCategory != ErrorCategoryProvider {
+			t.Errorf("Expected a provider error, got %v (%v)", failed[0].Err, failed[0].ErrorCategory)
+		}
+
+		if batch.Succeeded != 3 || batch.Failed != 2 {
+			t.Errorf("Expected 3 succeeded and 2 failed, got %v and %v", batch.Succeeded, batch.Failed)
+		}
+
+		results := batch.Results()
+
+		if results[0].PredictedClass != "ok" || results[1].Probability != -1 || results[4].PredictedClass != "ok" {
+			t.Errorf("Expected results for the successful items and placeholders for the failed ones, got %v", results)
+		}
+	})
+}
+
+func TestPredictManyConcurrency(t *testing.T) {
+	t.Run("Classifies all texts with several workers", func(t *testing.T) {
+		provider := NewFakeProviderFunc(func(request ChatRequest) (string, error) {
+			if strings.Contains(request.LastUserMessage(), "Woof") {
+				return `{"predicted_class": "dog", "probability": 0.9}`, nil
+			}
+
+			return `{"predicted_class": "cat", "probability": 0.9}`, nil
+		})
+
+		classifier := newTestClassifier(t, TaoClassifierOptions{Provider: provider, Concurrency: 3})
+
+		classifier.PromptTrain(map[Label][]LabelDescription{
+			"cat": {"meows"},
+			"dog": {"barks"},
+		})
+
+		texts := []string{"Meow", "Woof", "Meow", "Woof", "Woof"}
+
+		batch, err := classifier.PredictMany(texts)
+
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+
+		results := batch.Results()
+
+		for index, text := range texts {
+			expected := "cat"
+
+			if text == "Woof" {
+				expected = "dog"
+			}
+
+			if results[index].PredictedClass != expected {
+				t.Errorf("Expected %v for %v, got %v", expected, text, results[index].PredictedClass)
+			}
+		}
+	})
+}
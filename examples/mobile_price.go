@@ -13,19 +13,31 @@ func MobilePriceExample() {
 		TargetColumn:        "price_range",
 		Verbose:             false,
 		PromptSampleSize:    2,
+		Concurrency:         4,
 	}
 
 	fmt.Println("Initializing the classifier. ")
-	classifier := LLMClassifier.NewTaoClassifier(params)
+	classifier, err := LLMClassifier.NewTaoClassifier(params)
+
+	if err != nil {
+		println("Error: Failed to initialize classifier", err)
+		return
+	}
 
 	fmt.Println("Loading model if exists.")
 
-	_, err := classifier.LoadModel("mobile_price_classifier")
+	_, err = classifier.LoadModel("mobile_price_classifier")
 
 	if err != nil {
 		println("Error: Failed to load model", err)
 		fmt.Println("Training the classifier. This may take a while. ")
-		classifier.Train()
+
+		err = classifier.Train()
+
+		if err != nil {
+			println("Error: Failed to train classifier", err)
+			return
+		}
 	}
 
 	fmt.Println("Saving the model. ")
@@ -52,9 +64,17 @@ func MobilePriceExample() {
 		println("PredictManyObjects failed. Error:", err)
 	}
 
-	for index, prediction := range predictions {
-		fmt.Println("RowItem: ", testSet[index])
-		fmt.Println("Prediction: ", prediction)
+	for _, item := range predictions.Items {
+		fmt.Println("RowItem: ", testSet[item.Index])
+
+		if !item.OK() {
+			fmt.Println("Prediction failed: ", item.ErrorCategory, item.Err)
+			continue
+		}
+
+		fmt.Println("Prediction: ", item.Result)
 	}
 
+	fmt.Printf("Succeeded: %d, Failed: %d\n", predictions.Succeeded, predictions.Failed)
+
 }
@@ -12,9 +12,17 @@ func TwitterSentimentAnalysisExample() {
 		ModelId:          "twitter_sentiment_analysis",
 		TargetColumn:     "Sentiment",
 		PromptSampleSize: 2,
+		// irrelevant tweets are neither positive, neutral nor negative, abstain instead of forcing a label
+		MinConfidence:          0.6,
+		OutOfDistributionCheck: true,
 	}
 
-	classifier := LLMClassifier.NewTaoClassifier(params)
+	classifier, err := LLMClassifier.NewTaoClassifier(params)
+
+	if err != nil {
+		println("Error: Failed to initialize classifier", err)
+		panic(err)
+	}
 
 	classifier.PromptTrain(map[LLMClassifier.Label][]LLMClassifier.LabelDescription{
 		"positive": {"positive sentiment"},
@@ -43,16 +51,29 @@ func TwitterSentimentAnalysisExample() {
 	predictions, err := classifier.PredictManyRowItems(testSetWithoutSentiment)
 
 	fmt.Println("Test set: ", len(testSetWithoutSentiment))
-	fmt.Println("Predictions: ", len(predictions))
+	fmt.Println("Predictions: ", len(predictions.Items))
 
 	if err != nil {
 		println("PredictManyObjects failed. Error:", err)
 		panic(err)
 	}
 
-	for index, prediction := range predictions {
-		fmt.Printf("RowItem: %+v\n", testSetWithoutSentiment[index])
-		fmt.Printf("Prediction: %+v\n", prediction)
+	for _, item := range predictions.Items {
+		fmt.Printf("RowItem: %+v\n", testSetWithoutSentiment[item.Index])
+
+		if !item.OK() {
+			fmt.Printf("Prediction failed (%s): %v\n", item.ErrorCategory, item.Err)
+			continue
+		}
+
+		if item.Result.Abstained {
+			fmt.Printf("Abstained (%s)\n", item.Result.AbstainReason)
+			continue
+		}
+
+		fmt.Printf("Prediction: %+v\n", item.Result)
 	}
 
+	fmt.Printf("Succeeded: %d, Failed: %d, Abstained: %d\n", predictions.Succeeded, predictions.Failed, predictions.Abstained)
+
 }
@@ -3,8 +3,11 @@ module github.com/AdiPat/llm-classifier-go
 go 1.22.3
 
 require (
-	github.com/joho/godotenv v1.5.1 // indirect
-	github.com/openai/openai-go v0.1.0-alpha.19 // indirect
+	github.com/joho/godotenv v1.5.1
+	github.com/openai/openai-go v0.1.0-alpha.19
+)
+
+require (
 	github.com/tidwall/gjson v1.14.4 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.1 // indirect